@@ -2,26 +2,190 @@ package main
 
 import (
 	"log"
+	"net/netip"
 	"net/url"
 	"time"
 )
 
 //go:generate ufi --name=Product --out=ufi_product.go --pkg=main
+//ufi:exclusive q,name
+//ufi:together price-from,price-to
+//ufi:maxspan created 90d
+//ufi:version 2
 
 /*
 ufi
-qf-kind (multi-value,range,exact)
-qf-key
+kind (multi-value,range,exact; any,all,none for slice fields, keys get a -any, -all or -none suffix;
+      map for map fields; text for strings; geo for latitudes; in-cidr for netip.Addr, the key gets
+      an -in suffix and takes comma separated prefixes, e.g. src-in=10.0.0.0/8,192.168.0.0/16)
+key
+min, max (numbers)
+enum (strings and numbers, comma separated)
+len, minlen, maxlen, pattern (strings)
+default (exact, multi-value), default-from, default-to (range)
+required
+layout (time, Go layout; RFC3339, date-only values and unix timestamps are accepted without it)
+tz (time, time zone of values sent without one; WithFilterLocation sets it per call)
+parser (any type, name of a parser registered with RegisterFilterParser)
+unit (numbers, base unit of the field: a byte unit (B, KB, MiB, ...), a time unit (ns, us, ms, s, m, h)
+      or si for dimensionless k, M, G, ... suffixes; values like 10MB or 1.5k are converted into it)
 
-ufi: "qf-kind:...[,...];qf-key=..."
+time.Duration values are parsed with time.ParseDuration.
+
+scale (integers, decimal places of minor units: with scale=2, amount-to=19.99 is 1999 cents, min, max
+       and default values are read the same way, values with more decimal places are rejected)
+decimal (strings holding decimal numbers, compared exactly rather than as text or floats; the
+         query values are checked and passed to Where as they were sent)
+locale (numbers and decimals, en, de, fr, ru or ch: the format users type numbers in, e.g. 1 299,50
+        for ru; WithFilterLocale sets it per call, numbers are read in Go syntax without either. Lists of
+        numbers written in a locale with a decimal comma are separated by |, e.g. skus=1.299|5 for de, other
+        lists keep commas, so skus=1,299 is two values for en; values mixing formats such as 1.299,50 for en fail)
+
+Other types are parsed, in this order, by the function registered with RegisterFilterParser under
+the name of the parser option, by their UnmarshalText or as their underlying built-in type.
+Ranges of types without a built-in order need a Compare(T) int method. netip.Addr has one, so IP
+ranges follow address order.
+Fields of nested structs are filtered when the struct field is tagged with a key and no kind,
+their keys are prefixed with it, e.g. dimensions.weight-from. Fields of embedded structs are filtered
+without a prefix. Pointers on the way are followed safely: Match fails for nil ones.
+
+Map fields with string keys are filtered with the map kind by key[name] query keys, e.g. attr[color]=red,
+every named value must match.
+attrs (map, allowed names, comma separated; a name may be followed by :int, :float or :bool to check
+       and compare its values as that type, e.g. color,weight:float)
+
+String fields are searched with the text kind: the query is split into lower cased terms of letters
+and digits, stopwords (FilterStopwords, WithFilterStopwords) are left out and every term must be
+present. ProductTextIndex answers text filters of large slices without scanning every item.
+
+The geo kind filters a float latitude field paired with the longitude field named by the lon option:
+near=lat,lon&radius=5km keeps items within the radius (m, km or mi, meters without a unit) by haversine
+distance, bbox=minLat,minLon,maxLat,maxLon keeps items inside the box. Keys get a key- prefix when the
+key option is set. SortByLatDistance orders items by their distance to the near point. Where renders
+both as plain latitude and longitude bounds, so radius results hold items up to the corners of its box.
+
+column (SQL column used by Where, the snake cased field name by default; nested columns are prefixed
+        with the column of the struct field and an underscore, or nothing when it ends with a dot)
+
+Fields of named types with constants, see ProductKind, only accept the values of the constants
+unless the enum option is set. The values are listed in FilterEnums and in the getter docs.
+
+Time values also accept relative expressions: an anchor (now, today, yesterday, tomorrow,
+startOfDay/Week/Month/Year, endOfDay/Week/Month/Year) followed by offsets with s, m, h, d, w, M or y
+units, e.g. now-7d, now+1h30m or startOfMonth-1M. They resolve against WithFilterClock, time.Now by default.
+
+ufi: "kind=...[,...];key=...[;constraint=...][;default=...][;required]"
+
+ParseFilters reads the query of a URL, ParseFiltersQuery a raw query, ParseFiltersValues url.Values and
+ParseFiltersRequest the query and form body of a request, preferring form values for keys sent in both
+unless WithFilterPrecedence(FilterPrecedenceQuery) is set.
+ParseFiltersJSON reads JSON search documents, e.g. {"skus": {"in": [1, 2]}, "price": {"gte": 10},
+"attr": {"color": "red"}}, with the operators eq, in, gte, lte, any, all, none, match, in-cidr, near,
+radius and bbox; they give the same filters and errors as the equivalent query.
+ProductFilterMiddleware parses the filters of every request with ParseFiltersRequest into its context,
+handlers read them with ProductFilterFromContext. Invalid filters are answered with 400 Bad Request, WithFilterErrorResponder
+replaces the response, e.g. with FilterProblemResponder for RFC 7807 application/problem+json documents
+listing every invalid query key with a stable code (the FilterConstraint) and a reason, which FilterMessages
+localise.
+
+Filters are saved with json.Marshal as the query they were parsed from and loaded with json.Unmarshal,
+which parses it again. The payload holds FilterSchemaVersion, filters saved with older versions are
+upgraded by the functions registered with RegisterFilterMigration, see init.
+
+ProductSubscriptionIndex stores filters, e.g. of saved searches with alerts, and returns the IDs of
+those a new Product matches without evaluating each: exact and multi-value conditions are looked up in
+hash buckets and ranges in interval trees, only filters with other conditions are checked with Match.
+
+ProductIndex holds large slices of Product for filtering without scanning them: exact and multi-value
+fields are kept in hash indexes and range fields in sorted indexes. Search intersects the bitmaps of
+the indexed conditions of a filter, most selective first, and checks the rest with Match. Items are
+added with Insert and removed with Delete.
+
+Facets counts the values of exact and multi-value fields of the items matching a filter, and the
+histograms of range fields with the buckets option; FacetQueries renders the same as GROUP BY queries.
+The facet of a field leaves out the filters of that field, so its other values keep their counts.
+buckets (range, numbers: ascending edges, e.g. 50,100,500 for below 50, 50 to 100, 100 to 500 and
+         500 or more; times: hour, day, week, month or year, periods in the tz or WithFilterLocation)
+
+The fields parameter selects the fields of the response, e.g. fields=name,price: Project and ProjectMap
+reduce a Product to them for JSON output and Columns lists their SQL columns, every field when it is not
+sent. Names that cannot be selected fail with FilterConstraintFields.
+select (allows selecting the field by its key, or by the given name, e.g. select=title, which fields
+        without a key need)
+
+Struct level rules are declared next to the go:generate line:
+//ufi:exclusive key,key[,...]   at most one of the keys may be set
+//ufi:together key,key[,...]    either all or none of the keys must be set
+//ufi:maxspan key span          range bounds may be at most span apart (90d, 12h, 100)
+//ufi:version n                 schema version of saved filters, 1 by default
+Every range also requires its -from value to be not greater than its -to value. A default bound
+conflicting with the bound sent in the query is dropped, spans are only checked between sent bounds.
 */
 
 type Product struct {
-	SKU       uint64 `ufi:"qf-kind=range,multi-value,exact;qf-key=skus"`
-	Name      string
-	CreatedAt time.Time
-	Age       uint
-	Price     float64
+	SKU       uint64        `ufi:"kind=range,multi-value,exact;key=skus;min=1;max=1000000;select"`
+	Name      string        `ufi:"kind=exact,multi-value;key=name;maxlen=64;pattern=^[a-z ]+$;select"`
+	Query     string        `ufi:"kind=text;key=q"`
+	Condition string        `ufi:"kind=exact;key=condition;enum=new,used;default=new"`
+	Status    string        `ufi:"kind=exact;key=status;required"`
+	CreatedAt time.Time     `ufi:"kind=range;key=created;default-from=now-30d;buckets=day;select"`
+	UpdatedAt time.Time     `ufi:"kind=range,exact;key=updated;layout=02.01.2006;tz=Europe/Berlin"`
+	Age       uint          `ufi:"kind=range;key=age;default-to=18"`
+	Price     float64       `ufi:"kind=range;key=price;buckets=50,100,500;select"`
+	Warranty  time.Duration `ufi:"kind=range;key=warranty;max=87600h"`
+	Size      uint64        `ufi:"kind=range,multi-value;key=size;unit=B;max=10GiB"`
+	Views     float64       `ufi:"kind=range;key=views;unit=si"`
+	LatencyMs int64         `ufi:"kind=range;key=latency;unit=ms"`
+	Currency  Currency      `ufi:"kind=exact,multi-value;key=currency"`
+	Rating    Rating        `ufi:"kind=range;key=rating;min=1;max=5"`
+	Version   Version       `ufi:"kind=range,exact;key=version"`
+	Color     Color         `ufi:"kind=exact;key=color;parser=color"`
+	Kind      ProductKind   `ufi:"kind=exact,multi-value;key=kind"`
+
+	Tags        []string `ufi:"kind=any,all,none;key=tags"`
+	CategoryIDs []uint64 `ufi:"kind=any;key=categories;min=1"`
+
+	Attributes  map[string]string `ufi:"kind=map;key=attr;attrs=color,size,weight:float"`
+	Description string            `ufi:"kind=text;key=search;maxlen=200"`
+
+	Lat float64 `ufi:"kind=geo;lon=Lon"`
+	Lon float64
+
+	AmountCents int64   `ufi:"kind=range,exact;key=amount;scale=2;max=10000"`
+	ListPrice   string  `ufi:"kind=range,multi-value;key=list-price;decimal;min=0"`
+	Discount    float64 `ufi:"kind=range,exact,multi-value;key=discount;locale=ru"`
+
+	Title string `ufi:"select=title"`
+
+	Dimensions Dimensions `ufi:"key=dimensions"`
+	Seller     *Seller    `ufi:"key=seller;column=s."`
+	Audit
+}
+
+type Dimensions struct {
+	Weight float64 `ufi:"kind=range;key=weight;column=weight_kg"`
+	Width  float64 `ufi:"kind=range;key=width"`
+}
+
+type Seller struct {
+	Country string `ufi:"kind=exact,multi-value;key=country;select"`
+	Rating  *int   `ufi:"kind=range;key=rating"`
+}
+
+type Audit struct {
+	Revision int        `ufi:"kind=range;key=revision"`
+	SourceIP netip.Addr `ufi:"kind=exact,range,in-cidr;key=src"`
+}
+
+func init() {
+	// version 2 renamed the sku key to skus
+	RegisterFilterMigration(1, func(q url.Values) error {
+		if q.Has("sku") {
+			q.Set("skus", q.Get("sku"))
+			q.Del("sku")
+		}
+		return nil
+	})
 }
 
 func main() {
@@ -30,7 +194,7 @@ func main() {
 	_ = dateFrom
 	_ = dateTo
 
-	sampleFilterURL := "https://o3.ru/products"
+	sampleFilterURL := "https://o3.ru/products?status=active"
 	_, err := url.Parse(sampleFilterURL)
 	if err != nil {
 		log.Fatalf("[%s] is not url: %v", sampleFilterURL, err)
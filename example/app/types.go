@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 code, parsed with its UnmarshalText.
+type Currency string
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(string(text))
+	if len(s) != 3 {
+		return fmt.Errorf("invalid currency %q", text)
+	}
+	*c = Currency(s)
+	return nil
+}
+
+// Rating is parsed as its underlying int.
+type Rating int
+
+// Version is a major.minor version, ordered by Compare so it can be filtered by range.
+type Version struct {
+	Major, Minor int
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+	major, minor, _ := strings.Cut(string(text), ".")
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil {
+		return err
+	}
+	if minor == "" {
+		v.Minor = 0
+		return nil
+	}
+	v.Minor, err = strconv.Atoi(minor)
+	return err
+}
+
+func (v Version) Compare(other Version) int {
+	if v.Major != other.Major {
+		return v.Major - other.Major
+	}
+	return v.Minor - other.Minor
+}
+
+// ProductKind values are limited to the constants below.
+type ProductKind string
+
+const (
+	ProductKindPhysical ProductKind = "physical"
+	ProductKindDigital  ProductKind = "digital"
+	ProductKindService  ProductKind = "service"
+)
+
+// Color is parsed by the parser registered as "color".
+type Color struct {
+	R, G, B uint8
+}
+
+func init() {
+	RegisterFilterParser("color", func(s string) (Color, error) {
+		var c Color
+		if _, err := fmt.Sscanf(strings.TrimPrefix(s, "#"), "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			return Color{}, fmt.Errorf("invalid color %q", s)
+		}
+		return c, nil
+	})
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// validateCIDRKind checks that the in-cidr kind is used on netip.Addr fields.
+func validateCIDRKind(field _field) error {
+	if !hasKind(field, _qfKindInCIDR) {
+		return nil
+	}
+	if field._slice || field._map || field._goType != "netip.Addr" {
+		return fmt.Errorf("in-cidr kind requires a netip.Addr field")
+	}
+	return nil
+}
+
+// generateCIDRParser generates the parsing of the comma separated prefixes of an in-cidr filter.
+func generateCIDRParser(variable string, field _field, pf parserField, qfKeyConstName string) string {
+	const tmpl = `
+if q.Has($key) {
+	$keyRaw := q.Get($key)
+	if $keyParsed, err := gsliceparse($keyRaw, _filterParsePrefix); err != nil {
+		errs.add(&FilterError{Field: "$errField", Key: $key, Constraint: FilterConstraintType, Value: $keyRaw, Err: err})
+	} else {
+		$var.$parserField = &$keyParsed
+	}
+}
+`
+	return namedReplace(tmpl, map[string]string{
+		"$key":         qfKeyConstName,
+		"$errField":    fieldDisplayName(field),
+		"$var":         variable,
+		"$parserField": pf._name,
+	})
+}
+
+const filterCIDRFuncs = `
+// _filterParsePrefix parses a CIDR prefix, masking its host bits. An address is a prefix of its own.
+// IPv4-mapped IPv6 prefixes of at least 96 bits are turned into IPv4 prefixes.
+func _filterParsePrefix(inp string) (netip.Prefix, error) {
+	inp = strings.TrimSpace(inp)
+	var p netip.Prefix
+	if !strings.Contains(inp, "/") {
+		addr, err := netip.ParseAddr(inp)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		p = netip.PrefixFrom(addr, addr.BitLen())
+	} else {
+		var err error
+		if p, err = netip.ParsePrefix(inp); err != nil {
+			return netip.Prefix{}, err
+		}
+	}
+	if p.Addr().Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked(), nil
+}
+
+// _filterInPrefixes reports whether any of prefixes contains addr. IPv4-mapped IPv6 addresses
+// are compared as IPv4.
+func _filterInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}`
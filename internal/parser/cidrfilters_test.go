@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateCIDRKind(t *testing.T) {
+	t.Parallel()
+
+	kinds := func(kinds ...queryFilterKind) utiQueryFilter {
+		return utiQueryFilter{_kindList: kinds}
+	}
+	require.NoError(t, validateCIDRKind(_field{_goType: "netip.Addr", _qf: kinds(_qfKindExact, _qfKindRange, _qfKindInCIDR)}))
+	require.NoError(t, validateCIDRKind(_field{_goType: "netip.Prefix", _qf: kinds(_qfKindExact)}))
+	require.Error(t, validateCIDRKind(_field{_goType: "netip.Prefix", _qf: kinds(_qfKindInCIDR)}))
+	require.Error(t, validateCIDRKind(_field{_goType: "netip.Addr", _slice: true, _qf: kinds(_qfKindInCIDR)}))
+}
+
+func Test_generateCIDRParser(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "SourceIP", _goType: "netip.Addr", _qf: utiQueryFilter{_key: "src"}}
+	pf := parserField{_name: "_SourceIPInCIDR", _kind: _qfKindInCIDR}
+
+	// Act
+	got := generateCIDRParser("res", field, pf, "_SourceIPKey_in")
+
+	// Assert
+	require.Contains(t, got, "if _SourceIPKey_inParsed, err := gsliceparse(_SourceIPKey_inRaw, _filterParsePrefix); err != nil {")
+	require.Contains(t, got, "res._SourceIPInCIDR = &_SourceIPKey_inParsed")
+	require.Equal(t, "src-in", queryKey(field, pf))
+}
+
+func TestCIDRFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\nimport \"net/netip\"\n\ntype Host struct {\n" +
+		"\tIP netip.Addr `ufi:\"kind=in-cidr;key=ip\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+func main() {
+	v4 := Host{IP: netip.MustParseAddr("10.0.0.1")}
+	mapped := Host{IP: netip.MustParseAddr("::ffff:10.0.0.1")}
+	v6 := Host{IP: netip.MustParseAddr("2001:db8::1")}
+	for _, query := range []string{
+		"ip-in=10.0.0.0/8",
+		"ip-in=::ffff:10.0.0.0/104",
+		"ip-in=::ffff:10.0.0.1",
+		"ip-in=192.168.0.0/16,2001:db8::/32",
+		"ip-in=10.0.0.0/33",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(query, f.Match(&v4), f.Match(&mapped), f.Match(&v6))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Host", main)
+
+	// Assert
+	require.Equal(t, `ip-in=10.0.0.0/8 true true false
+ip-in=::ffff:10.0.0.0/104 true true false
+ip-in=::ffff:10.0.0.1 true true false
+ip-in=192.168.0.0/16,2001:db8::/32 false false true
+filter "ip-in" (field IP): invalid value "10.0.0.0/33": netip.ParsePrefix("10.0.0.0/33"): prefix length out of range
+`, got)
+}
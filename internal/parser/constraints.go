@@ -0,0 +1,434 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type constraintKind string
+
+const (
+	_constraintMin     = constraintKind("min")
+	_constraintMax     = constraintKind("max")
+	_constraintEnum    = constraintKind("enum")
+	_constraintLen     = constraintKind("len")
+	_constraintMinLen  = constraintKind("minlen")
+	_constraintMaxLen  = constraintKind("maxlen")
+	_constraintPattern = constraintKind("pattern")
+)
+
+// _constraintConstMap maps a tag constraint to the name of the generated FilterConstraint constant.
+var _constraintConstMap = map[constraintKind]string{
+	_constraintMin:     "FilterConstraintMin",
+	_constraintMax:     "FilterConstraintMax",
+	_constraintEnum:    "FilterConstraintEnum",
+	_constraintLen:     "FilterConstraintLen",
+	_constraintMinLen:  "FilterConstraintMinLen",
+	_constraintMaxLen:  "FilterConstraintMaxLen",
+	_constraintPattern: "FilterConstraintPattern",
+}
+
+func isValidConstraintKind(kind string) bool {
+	_, ok := _constraintConstMap[constraintKind(kind)]
+	return ok
+}
+
+type valueConstraint struct {
+	_kind  constraintKind
+	_value string
+}
+
+// fieldConstraints returns the constraints declared in the tag of the field. Fields of named types
+// with constants and without an enum constraint are limited to the values of the constants.
+func fieldConstraints(field _field) []valueConstraint {
+	constraints := field._qf._constraints
+	for _, c := range constraints {
+		if c._kind == _constraintEnum {
+			return constraints
+		}
+	}
+	if field._type == nil || len(field._type._enum) == 0 {
+		return constraints
+	}
+	if class := classifyGoType(fieldBasicType(field)); class != _goTypeClassString && !isNumericClass(class) {
+		return constraints
+	}
+	// enum values are comma separated, such constants cannot be listed
+	for _, value := range field._type._enum {
+		if strings.Contains(value, ",") {
+			return constraints
+		}
+	}
+	enum := valueConstraint{_kind: _constraintEnum, _value: strings.Join(field._type._enum, ",")}
+	return append(constraints[:len(constraints):len(constraints)], enum)
+}
+
+// fieldEnumValues returns the values the field is limited to, nil if it is not an enum.
+func fieldEnumValues(field _field) []string {
+	for _, c := range fieldConstraints(field) {
+		if c._kind == _constraintEnum {
+			return strings.Split(c._value, ",")
+		}
+	}
+	return nil
+}
+
+// generateFilterEnumsDef lists the values of enum fields by their exact and multi-value query keys.
+func generateFilterEnumsDef(fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) string {
+	enums := make(map[string][]string)
+	for _, field := range fields {
+		values := fieldEnumValues(field)
+		if values == nil {
+			continue
+		}
+		for _, pf := range structFieldMap[field._originalName] {
+			if pf._kind == _qfKindExact || isListKind(pf._kind) {
+				enums[qfConstKeyMap[pf]] = values
+			}
+		}
+	}
+	if len(enums) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(enums))
+	for key := range enums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := []string{
+		"// FilterEnums lists the values accepted by enum filters, by query key.",
+		"var FilterEnums = map[string][]string{",
+	}
+	for _, key := range keys {
+		quoted := make([]string, 0, len(enums[key]))
+		for _, value := range enums[key] {
+			quoted = append(quoted, strconv.Quote(value))
+		}
+		rows = append(rows, fmt.Sprintf("%s: {%s},", key, strings.Join(quoted, ", ")))
+	}
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n")
+}
+
+// generateEnumGetterDoc documents the values a getter of an enum field returns.
+func generateEnumGetterDoc(field _field, pf parserField, postfix string) string {
+	values := fieldEnumValues(field)
+	if values == nil {
+		return ""
+	}
+	what := ternary(isListKind(pf._kind), "values are", "value is")
+	return fmt.Sprintf("\n// Get%s%s returns the filter of %s, its %s one of: %s.\n",
+		field._originalName, postfix, queryKey(field, pf), what, strings.Join(values, ", "))
+}
+
+type goTypeClass int
+
+const (
+	_goTypeClassOther goTypeClass = iota
+	_goTypeClassInt
+	_goTypeClassUint
+	_goTypeClassFloat
+	_goTypeClassString
+	_goTypeClassDuration
+)
+
+func classifyGoType(goType string) goTypeClass {
+	switch goType {
+	case "int", "int32", "int64":
+		return _goTypeClassInt
+	case "uint", "uint32", "uint64":
+		return _goTypeClassUint
+	case "float32", "float64":
+		return _goTypeClassFloat
+	case "string":
+		return _goTypeClassString
+	case "time.Duration":
+		return _goTypeClassDuration
+	}
+	return _goTypeClassOther
+}
+
+func isNumericClass(class goTypeClass) bool {
+	return class == _goTypeClassInt || class == _goTypeClassUint || class == _goTypeClassFloat || class == _goTypeClassDuration
+}
+
+// numericLiteral validates that s is a valid value of the numeric field and returns it as
+// a literal that can be pasted into generated code. Values with units are converted into the base unit.
+func numericLiteral(field _field, s string) (string, error) {
+	class := classifyGoType(fieldBasicType(field))
+	if class == _goTypeClassDuration {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("time.Duration(%d)", d), nil
+	}
+	if field._qf._unit != "" && isNumericClass(class) {
+		var err error
+		if s, err = unitLiteral(field, s); err != nil {
+			return "", err
+		}
+	}
+	if field._qf._scale != "" && isNumericClass(class) {
+		var err error
+		if s, err = scaleLiteral(field, s); err != nil {
+			return "", err
+		}
+	}
+
+	var err error
+	switch class {
+	case _goTypeClassInt:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case _goTypeClassUint:
+		_, err = strconv.ParseUint(s, 10, 64)
+	case _goTypeClassFloat:
+		_, err = strconv.ParseFloat(s, 64)
+	default:
+		return "", fmt.Errorf("not a numeric type")
+	}
+	if err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func validatorFuncName(field _field) string {
+	return "_" + field._originalName + "Validate"
+}
+
+func patternVarName(field _field) string {
+	return "_" + field._originalName + "Pattern"
+}
+
+// generateValidatorCall generates code that validates an already parsed value
+// and appends the first violation to errs.
+func generateValidatorCall(field _field, pf parserField, key, parsedVar string) string {
+	const scalarTmpl = `if ferr := $validator($key, $parsed); ferr != nil {
+	errs.add(ferr)
+}`
+	const sliceTmpl = `for _, v := range $parsed {
+	if ferr := $validator($key, v); ferr != nil {
+		errs.add(ferr)
+		break
+	}
+}`
+	tmpl := ternary(isListKind(pf._kind), sliceTmpl, scalarTmpl)
+	return namedReplace(tmpl, map[string]string{
+		"$validator": validatorFuncName(field),
+		"$key":       key,
+		"$parsed":    parsedVar,
+	})
+}
+
+// generateValidatorFunc generates a function checking a single parsed value against
+// every constraint of the field, in the order they are declared in the tag.
+func generateValidatorFunc(field _field) (string, error) {
+	const funcTmpl = `
+$patternVar
+func $validator(key string, v $goType) *FilterError {
+	$checks
+	return nil
+}
+`
+	const violationTmpl = `return &FilterError{Field: "$errField", Key: key, Constraint: $constraint, Limit: $limit, Value: fmt.Sprint(v)}`
+	const condTmpl = `if $cond {
+	$violation
+}`
+	const enumTmpl = `switch v {
+case $values:
+default:
+	$violation
+}`
+
+	class := classifyGoType(fieldBasicType(field))
+	// values of named string types are converted for the string functions
+	str := ternary(field._goType == "string", "v", "string(v)")
+	var patternVar string
+	var checks []string
+	for _, c := range fieldConstraints(field) {
+		violation := namedReplace(violationTmpl, map[string]string{
+			"$errField":   fieldDisplayName(field),
+			"$constraint": _constraintConstMap[c._kind],
+			"$limit":      strconv.Quote(c._value),
+		})
+
+		var cond string
+		switch c._kind {
+		case _constraintMin, _constraintMax:
+			if field._qf._decimal {
+				lit, err := decimalLiteral(c._value)
+				if err != nil {
+					return "", fmt.Errorf("invalid %s constraint %q: %w", c._kind, c._value, err)
+				}
+				cond = fmt.Sprintf("_filterDecimalCmp(%s, %s) %s 0", str, lit, ternary(c._kind == _constraintMin, "<", ">"))
+				break
+			}
+			lit, err := numericLiteral(field, c._value)
+			if err != nil {
+				return "", fmt.Errorf("invalid %s constraint %q for type %s: %w", c._kind, c._value, field._goType, err)
+			}
+			cond = "v " + ternary(c._kind == _constraintMin, "<", ">") + " " + lit
+		case _constraintLen, _constraintMinLen, _constraintMaxLen:
+			if class != _goTypeClassString {
+				return "", fmt.Errorf("%s constraint is not supported for type %s", c._kind, field._goType)
+			}
+			if _, err := strconv.ParseUint(c._value, 10, 64); err != nil {
+				return "", fmt.Errorf("invalid %s constraint %q: %w", c._kind, c._value, err)
+			}
+			op := map[constraintKind]string{_constraintLen: "!=", _constraintMinLen: "<", _constraintMaxLen: ">"}[c._kind]
+			cond = fmt.Sprintf("utf8.RuneCountInString(%s) %s %s", str, op, c._value)
+		case _constraintPattern:
+			if class != _goTypeClassString {
+				return "", fmt.Errorf("pattern constraint is not supported for type %s", field._goType)
+			}
+			if _, err := regexp.Compile(c._value); err != nil {
+				return "", fmt.Errorf("invalid pattern constraint %q: %w", c._value, err)
+			}
+			patternVar = fmt.Sprintf("var %s = regexp.MustCompile(%s)", patternVarName(field), strconv.Quote(c._value))
+			cond = fmt.Sprintf("!%s.MatchString(%s)", patternVarName(field), str)
+		case _constraintEnum:
+			var values []string
+			for _, value := range strings.Split(c._value, ",") {
+				switch {
+				case class == _goTypeClassString:
+					values = append(values, strconv.Quote(value))
+				case isNumericClass(class):
+					lit, err := numericLiteral(field, value)
+					if err != nil {
+						return "", fmt.Errorf("invalid enum value %q for type %s: %w", value, field._goType, err)
+					}
+					values = append(values, lit)
+				default:
+					return "", fmt.Errorf("enum constraint is not supported for type %s", field._goType)
+				}
+			}
+			checks = append(checks, namedReplace(enumTmpl, map[string]string{
+				"$values":    strings.Join(values, ", "),
+				"$violation": violation,
+			}))
+			continue
+		}
+		checks = append(checks, namedReplace(condTmpl, map[string]string{
+			"$cond":      cond,
+			"$violation": violation,
+		}))
+	}
+
+	return namedReplace(funcTmpl, map[string]string{
+		"$patternVar": patternVar,
+		"$validator":  validatorFuncName(field),
+		"$goType":     field._goType,
+		"$checks":     strings.Join(checks, "\n"),
+	}), nil
+}
+
+const filterErrorDef = `
+// FilterConstraint names the rule a filter value failed.
+type FilterConstraint string
+
+const (
+	// FilterConstraintType is reported when a value cannot be parsed into the field type.
+	FilterConstraintType FilterConstraint = "type"
+	// FilterConstraintRequired is reported when a required filter is missing from the query.
+	FilterConstraintRequired FilterConstraint = "required"
+	FilterConstraintMin     FilterConstraint = "min"
+	FilterConstraintMax     FilterConstraint = "max"
+	FilterConstraintEnum    FilterConstraint = "enum"
+	FilterConstraintLen     FilterConstraint = "len"
+	FilterConstraintMinLen  FilterConstraint = "minlen"
+	FilterConstraintMaxLen  FilterConstraint = "maxlen"
+	FilterConstraintPattern FilterConstraint = "pattern"
+	// FilterConstraintRange is reported when the lower bound of a range is greater than the upper bound.
+	FilterConstraintRange FilterConstraint = "range"
+	// FilterConstraintMaxSpan is reported when the bounds of a range are too far apart.
+	FilterConstraintMaxSpan FilterConstraint = "maxspan"
+	// FilterConstraintExclusive is reported when mutually exclusive filters are combined.
+	FilterConstraintExclusive FilterConstraint = "exclusive"
+	// FilterConstraintTogether is reported when filters that must be sent together are sent partially.
+	FilterConstraintTogether FilterConstraint = "together"
+	// FilterConstraintAttr is reported when a map filter names an attribute that is not allowed.
+	FilterConstraintAttr FilterConstraint = "attr"
+	// FilterConstraintFields is reported when the fields parameter names a field that cannot be selected.
+	FilterConstraintFields FilterConstraint = "fields"
+)
+
+// FilterError describes a single query value that could not be parsed
+// or violates a constraint declared in the ufi tag.
+type FilterError struct {
+	// Field is the name of the filtered struct field.
+	Field string
+	// Key is the query key the value was read from.
+	Key string
+	// Constraint is the violated rule.
+	Constraint FilterConstraint
+	// Limit is the constraint argument as written in the tag, e.g. "1000" for max=1000.
+	Limit string
+	// Value is the offending value.
+	Value string
+	// Err is the underlying parse error, if any.
+	Err error
+}
+
+func (e *FilterError) Error() string {
+	switch e.Constraint {
+	case FilterConstraintRequired:
+		return fmt.Sprintf("filter %q (field %s) is required", e.Key, e.Field)
+	case FilterConstraintRange:
+		return fmt.Sprintf("filter %q (field %s): lower bound %q is greater than upper bound %q", e.Key, e.Field, e.Value, e.Limit)
+	case FilterConstraintMaxSpan:
+		return fmt.Sprintf("filter %q (field %s): range span %s exceeds %s", e.Key, e.Field, e.Value, e.Limit)
+	case FilterConstraintExclusive:
+		return fmt.Sprintf("filter %q (field %s): only one of %s may be set", e.Key, e.Field, e.Limit)
+	case FilterConstraintTogether:
+		return fmt.Sprintf("filter %q (field %s): %s must be set together", e.Key, e.Field, e.Limit)
+	case FilterConstraintAttr:
+		return fmt.Sprintf("filter %q (field %s): unknown attribute %q, allowed: %s", e.Key, e.Field, e.Value, e.Limit)
+	case FilterConstraintFields:
+		return fmt.Sprintf("filter %q: unknown field %q, allowed: %s", e.Key, e.Value, e.Limit)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("filter %q (field %s): invalid value %q: %v", e.Key, e.Field, e.Value, e.Err)
+	}
+	return fmt.Sprintf("filter %q (field %s): value %q violates %s=%s", e.Key, e.Field, e.Value, e.Constraint, e.Limit)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// FilterErrors is returned by ParseFilters when one or more query values are invalid.
+type FilterErrors []*FilterError
+
+func (e FilterErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add appends fe unless the same value was already reported for the key,
+// which happens when several kinds share one query key.
+func (e *FilterErrors) add(fe *FilterError) {
+	for _, existing := range *e {
+		if existing.Key == fe.Key && existing.Constraint == fe.Constraint && existing.Value == fe.Value {
+			return
+		}
+	}
+	*e = append(*e, fe)
+}
+
+func (e FilterErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+`
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateValidatorFunc(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got, err := generateValidatorFunc(_field{
+		_originalName: "Age",
+		_goType:       "uint",
+		_qf: utiQueryFilter{
+			_constraints: []valueConstraint{
+				{_kind: _constraintMin, _value: "1"},
+				{_kind: _constraintEnum, _value: "1,2"},
+			},
+		},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, `
+
+func _AgeValidate(key string, v uint) *FilterError {
+	if v < 1 {
+	return &FilterError{Field: "Age", Key: key, Constraint: FilterConstraintMin, Limit: "1", Value: fmt.Sprint(v)}
+}
+switch v {
+case 1, 2:
+default:
+	return &FilterError{Field: "Age", Key: key, Constraint: FilterConstraintEnum, Limit: "1,2", Value: fmt.Sprint(v)}
+}
+	return nil
+}
+`, got)
+}
+
+func Test_generateValidatorFunc_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		goType     string
+		constraint valueConstraint
+	}{
+		{name: "negative min for uint", goType: "uint64", constraint: valueConstraint{_kind: _constraintMin, _value: "-1"}},
+		{name: "max for string", goType: "string", constraint: valueConstraint{_kind: _constraintMax, _value: "10"}},
+		{name: "maxlen for int", goType: "int", constraint: valueConstraint{_kind: _constraintMaxLen, _value: "10"}},
+		{name: "invalid pattern", goType: "string", constraint: valueConstraint{_kind: _constraintPattern, _value: "(a"}},
+		{name: "enum for time", goType: "time.Time", constraint: valueConstraint{_kind: _constraintEnum, _value: "a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := generateValidatorFunc(_field{
+				_originalName: "Field",
+				_goType:       test.goType,
+				_qf:           utiQueryFilter{_constraints: []valueConstraint{test.constraint}},
+			})
+
+			// Assert
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_fieldEnumValues(t *testing.T) {
+	t.Parallel()
+
+	kind := _field{_goType: "Kind", _type: &fieldType{_underlying: "string", _enum: []string{"a", "b"}}}
+	require.Equal(t, []string{"a", "b"}, fieldEnumValues(kind))
+
+	kind._qf._constraints = []valueConstraint{{_kind: _constraintEnum, _value: "a"}}
+	require.Equal(t, []string{"a"}, fieldEnumValues(kind), "the enum option narrows the constants")
+
+	flag := _field{_goType: "Flag", _type: &fieldType{_underlying: "bool", _enum: []string{"true"}}}
+	require.Nil(t, fieldEnumValues(flag))
+	require.Nil(t, fieldEnumValues(_field{_goType: "string"}))
+}
+
+func Test_generateFilterEnumsDef(t *testing.T) {
+	t.Parallel()
+
+	field := _field{
+		_originalName: "Kind",
+		_goType:       "Kind",
+		_type:         &fieldType{_underlying: "string", _enum: []string{"b", "a"}},
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}, _key: "kind"},
+	}
+	exact := parserField{_name: "_KindExact", _kind: _qfKindExact}
+
+	// Act
+	got := generateFilterEnumsDef([]_field{field}, map[string][]parserField{"Kind": {exact}}, map[parserField]string{exact: "_KindKey"})
+
+	// Assert
+	require.Equal(t, `// FilterEnums lists the values accepted by enum filters, by query key.
+var FilterEnums = map[string][]string{
+_KindKey: {"b", "a"},
+}`, got)
+	require.Equal(t, "\n// GetKindExact returns the filter of kind, its value is one of: b, a.\n", generateEnumGetterDoc(field, exact, "Exact"))
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type valueParserKind int
+
+const (
+	// _valueParserBuiltin parses the built-in type of the field, see fieldBasicType.
+	_valueParserBuiltin valueParserKind = iota
+	// _valueParserText parses with the encoding.TextUnmarshaler of the field type.
+	_valueParserText
+	// _valueParserCustom parses with a function registered under the name of the parser tag option.
+	_valueParserCustom
+)
+
+// resolveValueParser picks how values of the field are parsed: a registered parser named in the tag
+// wins, then parsers of built-in types, then UnmarshalText and last the underlying type of named types,
+// so named types keep the validation of their UnmarshalText.
+func resolveValueParser(field _field) (valueParserKind, error) {
+	if field._qf._parser != "" {
+		if field._qf._unit != "" {
+			return 0, fmt.Errorf("unit option is not supported with a custom parser")
+		}
+		return _valueParserCustom, nil
+	}
+	if _, ok := goTypeParserFuncs[field._goType]; ok {
+		return _valueParserBuiltin, nil
+	}
+	if field._type != nil && field._type._textUnmarshaler {
+		if field._qf._unit != "" {
+			return 0, fmt.Errorf("unit option is not supported for encoding.TextUnmarshaler type %s", field._goType)
+		}
+		return _valueParserText, nil
+	}
+	if basic := fieldBasicType(field); basic != "time.Time" {
+		if _, ok := goTypeParserFuncs[basic]; ok {
+			return _valueParserBuiltin, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported type %s: implement encoding.TextUnmarshaler or set the parser option", field._goType)
+}
+
+// generateParseFunc generates a func(string) (T, error) expression parsing a single value of the field.
+func generateParseFunc(field _field, vp valueParserKind) string {
+	switch vp {
+	case _valueParserCustom:
+		return fmt.Sprintf("_filterCustomParse[%s](%s)", field._goType, strconv.Quote(field._qf._parser))
+	case _valueParserText:
+		return fmt.Sprintf("vtextparse[%s]", field._goType)
+	}
+
+	if field._qf._decimal {
+		return generateDecimalParseFunc(field)
+	}
+	basic := fieldBasicType(field)
+	parseFunc := fmt.Sprintf("%s[%s]", goTypeParserFuncs[basic], basic)
+	if basic != field._goType {
+		parseFunc = fmt.Sprintf("func(inp string) (%s, error) { v, err := %s(inp); return %s(v), err }",
+			field._goType, parseFunc, field._goType)
+	}
+	if field._qf._unit != "" {
+		parseFunc = generateUnitParseFunc(field, parseFunc)
+	}
+	if field._qf._scale != "" {
+		parseFunc = generateScaleParseFunc(field, parseFunc)
+	}
+	return parseFunc
+}
+
+const textParseFunc = `
+func vtextparse[T any, PT interface {
+	*T
+	encoding.TextUnmarshaler
+}](inp string) (T, error) {
+	var v T
+	err := PT(&v).UnmarshalText([]byte(inp))
+	return v, err
+}`
+
+const customParserRegistryDef = `
+var _filterParsers sync.Map
+
+// RegisterFilterParser registers parse under name for fields with the parser tag option,
+// e.g. ufi:"kind=exact;key=color;parser=color". Parsers are usually registered in init,
+// parsing a field without a registered parser fails.
+func RegisterFilterParser[T any](name string, parse func(string) (T, error)) {
+	_filterParsers.Store(name, parse)
+}
+
+func _filterCustomParse[T any](name string) func(string) (T, error) {
+	return func(inp string) (T, error) {
+		parser, ok := _filterParsers.Load(name)
+		if !ok {
+			return *new(T), fmt.Errorf("no filter parser registered as %q", name)
+		}
+		parse, ok := parser.(func(string) (T, error))
+		if !ok {
+			return *new(T), fmt.Errorf("filter parser %q does not return %T", name, *new(T))
+		}
+		return parse(inp)
+	}
+}`
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateParseFunc(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		field  _field
+		want   string
+		hasErr bool
+	}{
+		{name: "builtin", field: _field{_goType: "int64"}, want: "gintparse[int64]"},
+		{
+			name:  "named builtin",
+			field: _field{_goType: "Rating", _type: &fieldType{_underlying: "int"}},
+			want:  "func(inp string) (Rating, error) { v, err := gintparse[int](inp); return Rating(v), err }",
+		},
+		{
+			name:  "text unmarshaler wins over underlying type",
+			field: _field{_goType: "Currency", _type: &fieldType{_underlying: "string", _textUnmarshaler: true}},
+			want:  "vtextparse[Currency]",
+		},
+		{
+			name:  "registered parser wins",
+			field: _field{_goType: "Color", _type: &fieldType{_textUnmarshaler: true}, _qf: utiQueryFilter{_parser: "color"}},
+			want:  `_filterCustomParse[Color]("color")`,
+		},
+		{
+			name:  "named builtin with unit",
+			field: _field{_goType: "Bytes", _type: &fieldType{_underlying: "uint64"}, _qf: utiQueryFilter{_unit: "B"}},
+			want:  `func(inp string) (Bytes, error) { return gunitparse(inp, _filterByteUnits, "B", func(inp string) (Bytes, error) { v, err := guintparse[uint64](inp); return Bytes(v), err }) }`,
+		},
+		{name: "unknown type", field: _field{_goType: "Point"}, hasErr: true},
+		{name: "unit with text unmarshaler", field: _field{_goType: "Money", _type: &fieldType{_textUnmarshaler: true}, _qf: utiQueryFilter{_unit: "B"}}, hasErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			vp, err := resolveValueParser(test.field)
+
+			// Assert
+			if test.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, generateParseFunc(test.field, vp))
+		})
+	}
+}
+
+func Test_generateLessExpr_comparer(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got, ok := generateLessExpr(_field{_goType: "Version", _type: &fieldType{_comparer: true}}, "*res.a", "*res.b")
+
+	// Assert
+	require.True(t, ok)
+	require.Equal(t, "res.a.Compare(*res.b) < 0", got)
+
+	_, ok = generateLessExpr(_field{_goType: "Color", _type: &fieldType{}}, "a", "b")
+	require.False(t, ok)
+}
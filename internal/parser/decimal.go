@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// validateDecimal checks the scale option, allowed on integer fields holding minor units, and the
+// decimal option, allowed on string fields holding decimal numbers.
+func validateDecimal(field _field) error {
+	class := classifyGoType(fieldBasicType(field))
+	if field._qf._scale != "" {
+		if class != _goTypeClassInt && class != _goTypeClassUint {
+			return fmt.Errorf("scale option requires an integer field")
+		}
+		if scale, err := strconv.Atoi(field._qf._scale); err != nil || scale < 1 || scale > 18 {
+			return fmt.Errorf("invalid scale %q, expected 1 to 18", field._qf._scale)
+		}
+		if field._qf._unit != "" {
+			return fmt.Errorf("scale and unit options cannot be combined")
+		}
+	}
+	if field._qf._decimal {
+		if class != _goTypeClassString || field._qf._parser != "" || (field._type != nil && field._type._textUnmarshaler) {
+			return fmt.Errorf("decimal option requires a string field")
+		}
+		for _, kind := range field._qf._kindList {
+			if kind != _qfKindExact && kind != _qfKindMultiValue && kind != _qfKindRange {
+				return fmt.Errorf("decimal option is not supported for the %s kind", kind)
+			}
+		}
+	}
+	return nil
+}
+
+// scaleLiteral converts a tag literal such as 19.99 into minor units, 1999 for scale=2.
+func scaleLiteral(field _field, s string) (string, error) {
+	scale, err := strconv.Atoi(field._qf._scale)
+	if err != nil {
+		return "", err
+	}
+	v, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return "", fmt.Errorf("invalid decimal %q", s)
+	}
+	v.Mul(v, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !v.IsInt() {
+		return "", fmt.Errorf("%q has more than %d decimal places", s, scale)
+	}
+	return v.Num().String(), nil
+}
+
+// decimalLiteral validates a tag literal of a decimal field and quotes it.
+func decimalLiteral(s string) (string, error) {
+	if _, ok := new(big.Rat).SetString(s); !ok || strings.ContainsAny(s, "/eE") {
+		return "", fmt.Errorf("invalid decimal %q", s)
+	}
+	return strconv.Quote(s), nil
+}
+
+// generateScaleParseFunc wraps parseFunc of the field into a function reading decimals as minor units.
+func generateScaleParseFunc(field _field, parseFunc string) string {
+	return fmt.Sprintf("func(inp string) (%s, error) { return gscaleparse(inp, %s, %s) }", field._goType, field._qf._scale, parseFunc)
+}
+
+// generateDecimalParseFunc returns the parse function of a decimal field.
+func generateDecimalParseFunc(field _field) string {
+	if field._goType == "string" {
+		return "_filterParseDecimal"
+	}
+	return fmt.Sprintf("func(inp string) (%s, error) { v, err := _filterParseDecimal(inp); return %s(v), err }", field._goType, field._goType)
+}
+
+// generateDecimalLessExpr returns an expression reporting whether the decimal a is less than b.
+func generateDecimalLessExpr(field _field, a, b string) string {
+	return fmt.Sprintf("_filterDecimalCmp(%s, %s) < 0", fieldText(field, a), fieldText(field, b))
+}
+
+const genericScaleParseFunc = `
+// gscaleparse parses a decimal number into minor units with scale decimal places, exactly:
+// 19.99 is 1999 for a scale of 2. Values with more decimal places are rejected rather than rounded.
+func gscaleparse[T any](inp string, scale int, parse func(string) (T, error)) (T, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(inp), ".")
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > scale {
+		return *new(T), fmt.Errorf("%q has more than %d decimal places", inp, scale)
+	}
+	for _, r := range frac {
+		if r < '0' || r > '9' {
+			return *new(T), fmt.Errorf("invalid decimal %q", inp)
+		}
+	}
+	return parse(whole + frac + strings.Repeat("0", scale-len(frac)))
+}
+`
+
+const filterDecimalFuncs = `
+// _filterParseDecimal checks that inp is a decimal number, such as -19.99, and returns it trimmed.
+func _filterParseDecimal(inp string) (string, error) {
+	inp = strings.TrimSpace(inp)
+	if _, ok := _filterDecimal(inp); !ok {
+		return "", fmt.Errorf("invalid decimal %q", inp)
+	}
+	return inp, nil
+}
+
+func _filterDecimal(s string) (*big.Rat, bool) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || digits == "" || digits == "." || strings.Trim(digits, "0123456789.") != "" || strings.Count(digits, ".") > 1 {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// _filterDecimalCmp compares the decimals a and b exactly, like cmp.Compare. Values that are not
+// decimals compare as -2, less than and not equal to anything, so they fail every range and exact filter.
+func _filterDecimalCmp(a, b string) int {
+	x, okA := _filterDecimal(a)
+	y, okB := _filterDecimal(b)
+	if !okA || !okB {
+		return -2
+	}
+	return x.Cmp(y)
+}
+`
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateDecimal(t *testing.T) {
+	t.Parallel()
+
+	range_ := []queryFilterKind{_qfKindRange}
+	require.NoError(t, validateDecimal(_field{_goType: "int64", _qf: utiQueryFilter{_kindList: range_, _scale: "2"}}))
+	require.NoError(t, validateDecimal(_field{_goType: "string", _qf: utiQueryFilter{_kindList: range_, _decimal: true}}))
+	require.Error(t, validateDecimal(_field{_goType: "float64", _qf: utiQueryFilter{_kindList: range_, _scale: "2"}}))
+	require.Error(t, validateDecimal(_field{_goType: "int64", _qf: utiQueryFilter{_kindList: range_, _scale: "x"}}))
+	require.Error(t, validateDecimal(_field{_goType: "int64", _qf: utiQueryFilter{_kindList: range_, _scale: "2", _unit: "B"}}))
+	require.Error(t, validateDecimal(_field{_goType: "int64", _qf: utiQueryFilter{_kindList: range_, _decimal: true}}))
+	require.Error(t, validateDecimal(_field{_goType: "string", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindText}, _decimal: true}}))
+}
+
+func Test_scaleLiteral(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_goType: "int64", _qf: utiQueryFilter{_scale: "2"}}
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "19.99", want: "1999"},
+		{input: "-0.5", want: "-50"},
+		{input: "100", want: "10000"},
+		{input: "1.999", wantErr: true},
+		{input: "1e3", wantErr: true},
+	}
+	for _, test := range tests {
+		// Act
+		got, err := scaleLiteral(field, test.input)
+
+		// Assert
+		if test.wantErr {
+			require.Error(t, err, test.input)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, test.want, got)
+	}
+}
+
+func Test_generateParseFunc_decimal(t *testing.T) {
+	t.Parallel()
+
+	scaled := _field{_goType: "int64", _qf: utiQueryFilter{_scale: "2"}}
+	require.Equal(t, "func(inp string) (int64, error) { return gscaleparse(inp, 2, gintparse[int64]) }", generateParseFunc(scaled, _valueParserBuiltin))
+
+	decimal := _field{_goType: "Money", _type: &fieldType{_underlying: "string"}, _qf: utiQueryFilter{_decimal: true}}
+	require.Equal(t, "func(inp string) (Money, error) { v, err := _filterParseDecimal(inp); return Money(v), err }", generateParseFunc(decimal, _valueParserBuiltin))
+
+	less, ok := generateLessExpr(decimal, "x", "*f.a")
+	require.True(t, ok)
+	require.Equal(t, "_filterDecimalCmp(string(x), string(*f.a)) < 0", less)
+}
+
+func TestDecimalFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tAmountCents int64 `ufi:\"kind=range,exact;key=amount;scale=2\"`\n" +
+		"\tListPrice string `ufi:\"kind=range,exact,multi-value;key=price;decimal\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	items := []Product{
+		{AmountCents: 150, ListPrice: "1.50"},
+		{AmountCents: -1999, ListPrice: "-19.99"},
+		{AmountCents: -50, ListPrice: "-0.5"},
+	}
+	for _, query := range []string{
+		"price=1.5",
+		"price=1.500",
+		"price=-0.50",
+		"price-from=-19.99&price-to=-0.5",
+		"price-from=-0.49",
+		"price-to=-1",
+		"price=1.5,-20",
+		"amount=1.5",
+		"amount=-19.99",
+		"amount-from=-0.5&amount-to=1.50",
+		"amount-to=-0.51",
+		"amount=1.555",
+		"price=1.5.0",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		var matched []string
+		for i := range items {
+			if f.Match(&items[i]) {
+				matched = append(matched, items[i].ListPrice)
+			}
+		}
+		fmt.Println(query, matched)
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `price=1.5 [1.50]
+price=1.500 [1.50]
+price=-0.50 [-0.5]
+price-from=-19.99&price-to=-0.5 [-19.99 -0.5]
+price-from=-0.49 [1.50]
+price-to=-1 [-19.99]
+price=1.5,-20 [1.50]
+amount=1.5 [1.50]
+amount=-19.99 [-19.99]
+amount-from=-0.5&amount-to=1.50 [1.50 -0.5]
+amount-to=-0.51 [-19.99]
+filter "amount" (field AmountCents): invalid value "1.555": "1.555" has more than 2 decimal places
+filter "price" (field ListPrice): invalid value "1.5.0": invalid decimal "1.5.0"
+`, got)
+}
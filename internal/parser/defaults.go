@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func hasDefaults(fields []_field) bool {
+	for _, field := range fields {
+		if field._qf._default != nil || field._qf._defaultFrom != nil || field._qf._defaultTo != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parserFieldDefault returns the raw default of the query key backing pf:
+// default-from and default-to for range bounds, default otherwise.
+func parserFieldDefault(field _field, pf parserField) *string {
+	switch {
+	case pf.isRangeGte:
+		return field._qf._defaultFrom
+	case pf.isRangeLte:
+		return field._qf._defaultTo
+	}
+	return field._qf._default
+}
+
+// validateDefault checks defaults that can be checked at generation time.
+// Time values may be relative to the moment of parsing, so they are left to the generated parser.
+func validateDefault(field _field, pf parserField, value string) error {
+	values := []string{value}
+	if hasKind(field, _qfKindMultiValue) && pf._kind != _qfKindRange {
+		values = strings.Split(value, ",")
+	}
+	class := classifyGoType(fieldBasicType(field))
+	for _, v := range values {
+		var err error
+		switch {
+		case isNumericClass(class):
+			_, err = numericLiteral(field, v)
+		case fieldBasicType(field) == "bool":
+			_, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid default %q for type %s: %w", value, field._goType, err)
+		}
+	}
+	return nil
+}
+
+func generateDefaultsDef(fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) (string, error) {
+	defaults := make(map[string]string)
+	for _, field := range fields {
+		for _, pf := range structFieldMap[field._originalName] {
+			value := parserFieldDefault(field, pf)
+			if value == nil {
+				continue
+			}
+			if err := validateDefault(field, pf, *value); err != nil {
+				return "", fmt.Errorf("field %s: %w", field._originalName, err)
+			}
+			// exact and multi-value kinds share a key, so it is defaulted once
+			defaults[qfConstKeyMap[pf]] = *value
+		}
+	}
+	if len(defaults) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := []string{"var _filterDefaults = map[string]string{"}
+	for _, key := range keys {
+		rows = append(rows, fmt.Sprintf("%s: %s,", key, strconv.Quote(defaults[key])))
+	}
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n"), nil
+}
+
+const applyDefaultsCode = `
+q = maps.Clone(q)
+if q == nil {
+	q = make(url.Values)
+}
+for key, value := range _filterDefaults {
+	if !q.Has(key) {
+		q.Set(key, value)
+		if res._defaulted == nil {
+			res._defaulted = make(map[string]bool)
+		}
+		res._defaulted[key] = true
+	}
+}
+`
+
+// generateRequiredChecks generates checks reporting required fields none of which keys are present in the query
+// with a value. An empty value counts as missing, so it does not bypass the check.
+func generateRequiredChecks(fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) string {
+	const tmpl = `
+if $missing {
+	errs.add(&FilterError{Field: "$errField", Key: $key, Constraint: FilterConstraintRequired})
+}
+`
+	var rows []string
+	for _, field := range fields {
+		if !field._qf._required {
+			continue
+		}
+		var keys []string
+		seen := make(map[string]struct{})
+		for _, pf := range structFieldMap[field._originalName] {
+			key := qfConstKeyMap[pf]
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		missing := make([]string, 0, len(keys))
+		for _, key := range keys {
+			missing = append(missing, fmt.Sprintf("q.Get(%s) == \"\"", key))
+		}
+		// range only fields have no single key, they are reported by the key prefix
+		reportKey := ternary(len(keys) == 1, keys[0], strconv.Quote(field._qf._key))
+		rows = append(rows, namedReplace(tmpl, map[string]string{
+			"$missing":  strings.Join(missing, " && "),
+			"$errField": fieldDisplayName(field),
+			"$key":      reportKey,
+		}))
+	}
+	return strings.Join(rows, "")
+}
+
+func generateFieldPresenceFuncs(structRcv, structName string, field _field, pf parserField, postfix, qfKeyConstName string, withDefaults bool) string {
+	const setTmpl = `
+func ($rcv *$structName) Is$origField$postfixSet() bool {
+	return $rcv.$parserField != nil$notDefaulted
+}
+`
+	const defaultedTmpl = `
+func ($rcv *$structName) Is$origField$postfixDefaulted() bool {
+	return $rcv._defaulted[$key]
+}
+`
+	var notDefaulted string
+	if withDefaults {
+		notDefaulted = fmt.Sprintf(" && !%s._defaulted[%s]", structRcv, qfKeyConstName)
+	}
+	tmpl := setTmpl
+	if parserFieldDefault(field, pf) != nil {
+		tmpl += defaultedTmpl
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$notDefaulted": notDefaulted,
+		"$rcv":          structRcv,
+		"$structName":   structName,
+		"$origField":    field._originalName,
+		"$postfix":      postfix,
+		"$parserField":  pf._name,
+		"$key":          qfKeyConstName,
+	})
+}
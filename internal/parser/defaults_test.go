@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateDefaultsDef(t *testing.T) {
+	t.Parallel()
+
+	ageLte := parserField{_name: "_AgeLte", _kind: _qfKindRange, isRangeLte: true}
+	ageGte := parserField{_name: "_AgeGte", _kind: _qfKindRange, isRangeGte: true}
+	statusExact := parserField{_name: "_StatusExact", _kind: _qfKindExact}
+	statusMulti := parserField{_name: "_StatusMultiValue", _kind: _qfKindMultiValue}
+	fields := []_field{{
+		_originalName: "Age",
+		_goType:       "uint",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "age", _defaultTo: ptr("18")},
+	}, {
+		_originalName: "Status",
+		_goType:       "string",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact, _qfKindMultiValue}, _key: "status", _default: ptr("new")},
+	}}
+	fieldMap := map[string][]parserField{
+		"Age":    {ageLte, ageGte},
+		"Status": {statusExact, statusMulti},
+	}
+	constMap := map[parserField]string{
+		ageLte:      "_AgeKey_lte",
+		ageGte:      "_AgeKey_gte",
+		statusExact: "_StatusKey",
+		statusMulti: "_StatusKey",
+	}
+
+	// Act
+	got, err := generateDefaultsDef(fields, fieldMap, constMap)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, `var _filterDefaults = map[string]string{
+_AgeKey_lte: "18",
+_StatusKey: "new",
+}`, got)
+
+	// Act
+	fields[0]._qf._defaultTo = ptr("-1")
+	_, err = generateDefaultsDef(fields, fieldMap, constMap)
+
+	// Assert
+	require.Error(t, err)
+}
+
+func Test_generateRequiredChecks(t *testing.T) {
+	t.Parallel()
+
+	createdLte := parserField{_name: "_CreatedLte", _kind: _qfKindRange, isRangeLte: true}
+	createdGte := parserField{_name: "_CreatedGte", _kind: _qfKindRange, isRangeGte: true}
+
+	// Act
+	got := generateRequiredChecks([]_field{{
+		_originalName: "Created",
+		_goType:       "time.Time",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "created", _required: true},
+	}}, map[string][]parserField{
+		"Created": {createdLte, createdGte},
+	}, map[parserField]string{
+		createdLte: "_CreatedKey_lte",
+		createdGte: "_CreatedKey_gte",
+	})
+
+	// Assert
+	require.Equal(t, `
+if q.Get(_CreatedKey_lte) == "" && q.Get(_CreatedKey_gte) == "" {
+	errs.add(&FilterError{Field: "Created", Key: "created", Constraint: FilterConstraintRequired})
+}
+`, got)
+}
+
+func TestRequiredFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tStatus string `ufi:\"kind=exact;key=status;required\"`\n}\n"
+	const main = `package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func main() {
+	for _, query := range []string{"status=a", "status=", "status=&status=a", "name=a"} {
+		_, err := ParseFiltersQuery(query)
+		var errs FilterErrors
+		errors.As(err, &errs)
+		var constraints []FilterConstraint
+		for _, fe := range errs {
+			constraints = append(constraints, fe.Constraint)
+		}
+		fmt.Printf("%q %v\n", query, constraints)
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `"status=a" []
+"status=" [required]
+"status=&status=a" [required]
+"name=a" [required]
+`, got)
+}
@@ -0,0 +1,387 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// timeBucketUnits are the units the buckets option accepts for time fields.
+var timeBucketUnits = []string{"hour", "day", "week", "month", "year"}
+
+// usesDialect matches generated statements calling the dialect d.
+var usesDialect = regexp.MustCompile(`\bd\.`)
+
+func facetsName(origStructName string) string {
+	return origStructName + "Facets"
+}
+
+// facetCounts reports whether the values of the field are counted by Facets.
+func facetCounts(field _field) bool {
+	buckets, _ := subscriptionIndexing(field)
+	return buckets
+}
+
+// isTimeBuckets reports whether the buckets option of the field names a time unit.
+func isTimeBuckets(field _field) bool {
+	return slices.Contains(timeBucketUnits, field._qf._buckets)
+}
+
+// validateBuckets checks the buckets option, allowed on range fields of numbers, taking
+// ascending edges, and of times, taking a unit.
+func validateBuckets(field _field) error {
+	if field._qf._buckets == "" {
+		return nil
+	}
+	if !hasKind(field, _qfKindRange) || field._slice || field._map {
+		return fmt.Errorf("buckets option requires the range kind")
+	}
+	if fieldBasicType(field) == "time.Time" {
+		if !isTimeBuckets(field) {
+			return fmt.Errorf("invalid buckets %q, expected one of %s", field._qf._buckets, strings.Join(timeBucketUnits, ", "))
+		}
+		return nil
+	}
+	_, err := bucketEdges(field)
+	return err
+}
+
+// bucketEdges returns the literals of the ascending edges of the buckets option of a number field.
+func bucketEdges(field _field) ([]string, error) {
+	class := classifyGoType(fieldBasicType(field))
+	if field._qf._decimal || class != _goTypeClassInt && class != _goTypeClassUint && class != _goTypeClassFloat {
+		return nil, fmt.Errorf("buckets option requires a number or time field")
+	}
+	var edges []string
+	prev := 0.0
+	for i, s := range strings.Split(field._qf._buckets, ",") {
+		edge, err := numericLiteral(field, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket edge %q: %w", s, err)
+		}
+		v, _ := strconv.ParseFloat(edge, 64)
+		if i > 0 && v <= prev {
+			return nil, fmt.Errorf("bucket edges must ascend, %q does not", s)
+		}
+		prev = v
+		edges = append(edges, edge)
+	}
+	return edges, nil
+}
+
+// generateFacets generates the facets of the filtered struct, computed in memory by Facets and
+// with SQL by FacetQueries. It is empty when no field has a facet.
+func generateFacets(structRcv, structName, origStructName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// $facets holds the facets of the $origStruct items matching a filter: counts of the values of exact
+// and multi-value fields, most frequent first, and histograms of range fields with buckets.
+type $facets struct {
+	$fields
+}
+
+// Facets counts the facets of the items matching $rcv. A facet is disjunctive: it counts the items
+// matching every filter but the filters of its own field, so the other values of a field that is
+// filtered on stay selectable with their counts.
+func ($rcv *$structName) Facets(items []$origStruct) $facets {
+	$init
+	for i := range items {
+		v := &items[i]
+		failed := $rcv.failedField(v)
+		$counts
+	}
+	return $facets{
+		$results
+	}
+}
+
+// failedField returns the number of the only field whose filters v fails, -1 when v matches all
+// filters and -2 when it fails filters of several fields.
+func ($rcv *$structName) failedField(v *$origStruct) int {
+	failed := -1
+	for i, match := range [...]func() bool{
+		$matchers
+	} {
+		if match() {
+			continue
+		}
+		if failed != -1 {
+			return -2
+		}
+		failed = i
+	}
+	return failed
+}
+
+// FacetQueries renders the queries computing the facets of the rows of table matching $rcv, with
+// GROUP BY. Like Facets, the query of a facet leaves out the filters of its own field. Queries of
+// value counts return value and count rows, most frequent first, queries of histograms return
+// bucket and count rows ordered by bucket: the index of the bucket for numbers and the start of
+// the period for times. Empty buckets have no rows.
+func ($rcv *$structName) FacetQueries(d FilterDialect, table string) []FilterFacetQuery {
+	var queries []FilterFacetQuery
+	$queries
+	return queries
+}
+
+// facetWhere adds the conditions of the filters that are set to w, except the filters of the field
+// numbered skip, see failedField.
+func ($rcv *$structName) facetWhere(w *_filterWhere, skip int) {
+	$dialect
+	$where
+}
+`
+	var (
+		facetFields, inits, counts, results, matchers, queries, where []string
+		dialect                                                       string
+	)
+	for _, field := range fields {
+		pfs := structFieldMap[field._originalName]
+		block := generateMatchBlock(structRcv, field, pfs)
+		if block == "" {
+			continue
+		}
+		n := len(matchers)
+		matchers = append(matchers, fmt.Sprintf("func() bool {\n%s\nreturn true\n},", block))
+		if conds := generateWhereConds(structRcv, field, pfs); len(conds) > 0 {
+			joined := strings.Join(conds, "\n")
+			if usesDialect.MatchString(joined) {
+				dialect = "d := w.dialect"
+			}
+			where = append(where, fmt.Sprintf("if skip != %d {\n%s\n}", n, joined))
+		}
+
+		column := strconv.Quote(fieldColumn(field))
+		var adds []string
+		if facetCounts(field) {
+			name := field._originalName + "Values"
+			facetFields = append(facetFields, fmt.Sprintf("%s []FilterFacetValue[%s]", name, field._goType))
+			inits = append(inits, fmt.Sprintf("_%s := _filterNewCounter[%s]()", name, field._goType))
+			adds = append(adds, fmt.Sprintf("_%s.add(x)", name))
+			results = append(results, fmt.Sprintf("%s: _%s.values(),", name, name))
+			queries = append(queries, fmt.Sprintf("{\n\tw := &_filterWhere{dialect: d}\n\t%s.facetWhere(w, %d)\n\tqueries = append(queries, w.facetQuery(%q, %s, table, true))\n}",
+				structRcv, n, name, column))
+		}
+		if field._qf._buckets != "" {
+			name := field._originalName + "Buckets"
+			var group string
+			if isTimeBuckets(field) {
+				location := structRcv + "._options.timeLocation()"
+				if field._qf._timezone != "" {
+					location = timeLocationVarName(field)
+				}
+				facetFields = append(facetFields, fmt.Sprintf("%s []FilterBucket[time.Time]", name))
+				inits = append(inits, fmt.Sprintf("_%s := _filterNewTimeHistogram(%q, %s)", name, field._qf._buckets, location))
+				group = fmt.Sprintf("d.TimeBucket(%s, %q)", column, field._qf._buckets)
+			} else {
+				// validateBuckets has checked the edges
+				edges, _ := bucketEdges(field)
+				literal := fmt.Sprintf("[]%s{%s}", field._goType, strings.Join(edges, ", "))
+				facetFields = append(facetFields, fmt.Sprintf("%s []FilterBucket[%s]", name, field._goType))
+				inits = append(inits, fmt.Sprintf("_%s := _filterNewHistogram(%s)", name, literal))
+				group = fmt.Sprintf("_filterCaseBucket(w, %s, %s)", column, literal)
+			}
+			adds = append(adds, fmt.Sprintf("_%s.add(x)", name))
+			results = append(results, fmt.Sprintf("%s: _%s.buckets(),", name, name))
+			queries = append(queries, fmt.Sprintf("{\n\tw := &_filterWhere{dialect: d}\n\tgroup := %s\n\t%s.facetWhere(w, %d)\n\tqueries = append(queries, w.facetQuery(%q, group, table, false))\n}",
+				group, structRcv, n, name))
+		}
+		if len(adds) > 0 {
+			counts = append(counts, fmt.Sprintf("if failed == -1 || failed == %d {\n\tif x, ok := %s(v); ok {\n\t\t%s\n\t}\n}",
+				n, fieldValueFuncName(field), strings.Join(adds, "\n")))
+		}
+	}
+	if len(facetFields) == 0 {
+		return ""
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$facets":     facetsName(origStructName),
+		"$fields":     strings.Join(facetFields, "\n"),
+		"$rcv":        structRcv,
+		"$structName": structName,
+		"$origStruct": origStructName,
+		"$init":       strings.Join(inits, "\n"),
+		"$counts":     strings.Join(counts, "\n"),
+		"$results":    strings.Join(results, "\n"),
+		"$matchers":   strings.Join(matchers, "\n"),
+		"$queries":    strings.Join(queries, "\n"),
+		"$dialect":    dialect,
+		"$where":      strings.Join(where, "\n"),
+	})
+}
+
+const filterFacetFuncs = `
+// FilterFacetValue is a value of a field and the number of items holding it.
+type FilterFacetValue[T comparable] struct {
+	Value T
+	Count int
+}
+
+// FilterBucket is a bucket of a histogram, counting the values from From, inclusive, to To,
+// exclusive. A nil bound is unbounded.
+type FilterBucket[T any] struct {
+	From  *T
+	To    *T
+	Count int
+}
+
+// FilterFacetQuery is an SQL query computing a facet, see FacetQueries.
+type FilterFacetQuery struct {
+	// Facet is the name of the facet, the field of the facets struct the query computes.
+	Facet string
+	SQL   string
+	Args  []any
+}
+
+type _filterCounter[T comparable] struct {
+	index  map[T]int
+	counts []FilterFacetValue[T]
+}
+
+func _filterNewCounter[T comparable]() *_filterCounter[T] {
+	return &_filterCounter[T]{index: make(map[T]int)}
+}
+
+func (c *_filterCounter[T]) add(v T) {
+	i, ok := c.index[v]
+	if !ok {
+		i = len(c.counts)
+		c.index[v] = i
+		c.counts = append(c.counts, FilterFacetValue[T]{Value: v})
+	}
+	c.counts[i].Count++
+}
+
+// values returns the counted values, most frequent first and in the order they were seen on ties.
+func (c *_filterCounter[T]) values() []FilterFacetValue[T] {
+	slices.SortStableFunc(c.counts, func(a, b FilterFacetValue[T]) int {
+		return b.Count - a.Count
+	})
+	return c.counts
+}
+
+// _filterHistogram counts values in the buckets between ascending edges, the first and last
+// buckets are unbounded.
+type _filterHistogram[T cmp.Ordered] struct {
+	edges  []T
+	counts []int
+}
+
+func _filterNewHistogram[T cmp.Ordered](edges []T) *_filterHistogram[T] {
+	return &_filterHistogram[T]{edges: edges, counts: make([]int, len(edges)+1)}
+}
+
+func (h *_filterHistogram[T]) add(v T) {
+	i, found := slices.BinarySearch(h.edges, v)
+	if found {
+		i++
+	}
+	h.counts[i]++
+}
+
+func (h *_filterHistogram[T]) buckets() []FilterBucket[T] {
+	res := make([]FilterBucket[T], len(h.counts))
+	for i := range res {
+		res[i].Count = h.counts[i]
+		if i > 0 {
+			from := h.edges[i-1]
+			res[i].From = &from
+		}
+		if i < len(h.edges) {
+			to := h.edges[i]
+			res[i].To = &to
+		}
+	}
+	return res
+}
+
+// _filterTimeHistogram counts times in the periods of unit they fall in, in loc.
+type _filterTimeHistogram struct {
+	unit   string
+	loc    *time.Location
+	counts map[time.Time]int
+}
+
+func _filterNewTimeHistogram(unit string, loc *time.Location) *_filterTimeHistogram {
+	return &_filterTimeHistogram{unit: unit, loc: loc, counts: make(map[time.Time]int)}
+}
+
+func (h *_filterTimeHistogram) add(t time.Time) {
+	h.counts[_filterTruncateTime(t.In(h.loc), h.unit)]++
+}
+
+// buckets returns the periods holding times, in order.
+func (h *_filterTimeHistogram) buckets() []FilterBucket[time.Time] {
+	res := make([]FilterBucket[time.Time], 0, len(h.counts))
+	for _, from := range slices.SortedFunc(maps.Keys(h.counts), time.Time.Compare) {
+		to := _filterNextTime(from, h.unit)
+		res = append(res, FilterBucket[time.Time]{From: &from, To: &to, Count: h.counts[from]})
+	}
+	return res
+}
+
+// _filterTruncateTime returns the start of the period of unit holding t, weeks start on Monday.
+func _filterTruncateTime(t time.Time, unit string) time.Time {
+	y, m, d := t.Date()
+	switch unit {
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case "week":
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// _filterNextTime returns the start of the period of unit following the one starting at t.
+func _filterNextTime(t time.Time, unit string) time.Time {
+	switch unit {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// timeLocation returns the location times are bucketed in, UTC for filters that were not parsed.
+func (o *filterOptions) timeLocation() *time.Location {
+	if o == nil {
+		return time.UTC
+	}
+	return o.location
+}
+
+// _filterCaseBucket renders the index of the bucket between edges holding the value of column,
+// counted like _filterHistogram.
+func _filterCaseBucket[T any](w *_filterWhere, column string, edges []T) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i, edge := range edges {
+		fmt.Fprintf(&b, " WHEN %s < %s THEN %d", column, w.arg(edge), i)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", len(edges))
+	return b.String()
+}
+
+// facetQuery renders the query counting the rows of table matching the conditions of w by group.
+func (w *_filterWhere) facetQuery(facet, group, table string, byCount bool) FilterFacetQuery {
+	sql := "SELECT " + group + " AS ufi_facet, COUNT(*) AS ufi_count FROM " + table
+	if cond := w.String(); cond != "" {
+		sql += " WHERE " + cond
+	}
+	sql += " GROUP BY ufi_facet ORDER BY "
+	if byCount {
+		sql += "ufi_count DESC, "
+	}
+	return FilterFacetQuery{Facet: facet, SQL: sql + "ufi_facet", Args: w.args}
+}
+`
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateBuckets(t *testing.T) {
+	t.Parallel()
+
+	rangeKind := []queryFilterKind{_qfKindRange}
+	tests := []struct {
+		name    string
+		field   _field
+		wantErr bool
+	}{
+		{name: "edges", field: _field{_goType: "float64", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "0,50.5,100"}}},
+		{name: "time unit", field: _field{_goType: "time.Time", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "month"}}},
+		{name: "unknown unit", field: _field{_goType: "time.Time", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "decade"}}, wantErr: true},
+		{name: "descending", field: _field{_goType: "int", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "10,5"}}, wantErr: true},
+		{name: "not a number", field: _field{_goType: "int", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "1,x"}}, wantErr: true},
+		{name: "string", field: _field{_goType: "string", _qf: utiQueryFilter{_kindList: rangeKind, _buckets: "a,b"}}, wantErr: true},
+		{name: "exact", field: _field{_goType: "int", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}, _buckets: "1"}}, wantErr: true},
+	}
+	for _, test := range tests {
+		// Act
+		err := validateBuckets(test.field)
+
+		// Assert
+		require.Equal(t, test.wantErr, err != nil, test.name)
+	}
+}
+
+func Test_bucketEdges(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_goType: "int64", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _scale: "2", _buckets: "9.99, 100"}}
+
+	// Act
+	got, err := bucketEdges(field)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, []string{"999", "10000"}, got)
+}
+
+func Test_generateFacets(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "Name",
+		_goType:       "string",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}, _key: "name"},
+	}, {
+		_originalName: "Price",
+		_goType:       "float64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "price", _buckets: "10,100"},
+	}, {
+		_originalName: "Created",
+		_goType:       "time.Time",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "created", _buckets: "week", _timezone: "Europe/Berlin"},
+	}}
+	_, fieldMap := generateFilterStructDef("_ProductFilter", fields)
+
+	// Act
+	got := generateFacets("_Pr", "_ProductFilter", "Product", fields, fieldMap)
+
+	// Assert
+	require.Contains(t, got, "type ProductFacets struct {\n\tNameValues []FilterFacetValue[string]\nPriceBuckets []FilterBucket[float64]\nCreatedBuckets []FilterBucket[time.Time]\n}")
+	require.Contains(t, got, `_CreatedBuckets := _filterNewTimeHistogram("week", _CreatedLocation)`)
+	require.Contains(t, got, "if failed == -1 || failed == 1 {\n\tif x, ok := _PriceValue(v); ok {\n\t\t_PriceBuckets.add(x)\n\t}\n}")
+	require.Contains(t, got, "if skip != 0 {\nif _Pr._NameExact != nil {\n\tw.cmp(\"name\", \"=\", *_Pr._NameExact)\n}\n}")
+	require.Contains(t, got, `group := _filterCaseBucket(w, "price", []float64{10, 100})`)
+	require.Contains(t, got, `group := d.TimeBucket("created", "week")`)
+	require.NotContains(t, got, "d := w.dialect")
+
+	// Act
+	text := []_field{{
+		_originalName: "Description",
+		_goType:       "string",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindText}, _key: "q"},
+	}}
+	_, textFieldMap := generateFilterStructDef("_ProductFilter", text)
+	got = generateFacets("_Pr", "_ProductFilter", "Product", text, textFieldMap)
+
+	// Assert
+	require.Empty(t, got)
+}
+
+func TestFacets(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tColor string `ufi:\"kind=exact,multi-value;key=color\"`\n" +
+		"\tSize string `ufi:\"kind=exact;key=size\"`\n" +
+		"\tPrice float64 `ufi:\"kind=range;key=price;buckets=10,100\"`\n" +
+		"\tTitle string `ufi:\"kind=text;key=q\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	items := []Product{
+		{Color: "red", Size: "m", Price: 5, Title: "red shirt"},
+		{Color: "red", Size: "m", Price: 50, Title: "red shirt"},
+		{Color: "blue", Size: "m", Price: 50, Title: "blue shirt"},
+		{Color: "blue", Size: "m", Price: 500, Title: "blue shirt"},
+		{Color: "red", Size: "l", Price: 50, Title: "red shirt"},
+		{Color: "green", Size: "l", Price: 500, Title: "green hat"},
+	}
+	f, err := ParseFiltersQuery("color=red,blue&size=m&price-from=10&q=shirt")
+	if err != nil {
+		panic(err)
+	}
+	facets := f.Facets(items)
+	fmt.Println(facets.ColorValues)
+	fmt.Println(facets.SizeValues)
+	for _, b := range facets.PriceBuckets {
+		fmt.Print(b.Count, " ")
+	}
+	fmt.Println()
+	for _, q := range f.FacetQueries(FilterDialectPostgres, "products") {
+		fmt.Println(q.Facet, q.SQL, q.Args)
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	// each facet leaves out its own filter, the text filter has no facet
+	require.Equal(t, `[{blue 2} {red 1}]
+[{m 3} {l 1}]
+1 2 1 
+ColorValues SELECT color AS ufi_facet, COUNT(*) AS ufi_count FROM products WHERE size = $1 AND price >= $2 AND to_tsvector('simple', title) @@ to_tsquery('simple', $3) GROUP BY ufi_facet ORDER BY ufi_count DESC, ufi_facet [m 10 shirt]
+SizeValues SELECT size AS ufi_facet, COUNT(*) AS ufi_count FROM products WHERE color IN ($1, $2) AND price >= $3 AND to_tsvector('simple', title) @@ to_tsquery('simple', $4) GROUP BY ufi_facet ORDER BY ufi_count DESC, ufi_facet [red blue 10 shirt]
+PriceBuckets SELECT CASE WHEN price < $1 THEN 0 WHEN price < $2 THEN 1 ELSE 2 END AS ufi_facet, COUNT(*) AS ufi_count FROM products WHERE color IN ($3, $4) AND size = $5 AND to_tsvector('simple', title) @@ to_tsquery('simple', $6) GROUP BY ufi_facet ORDER BY ufi_facet [10 100 red blue m shirt]
+`, got)
+}
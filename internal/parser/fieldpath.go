@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fieldSteps returns the path of the field, fields read from source rows are direct fields.
+func fieldSteps(field _field) []pathStep {
+	if len(field._path) == 0 {
+		return []pathStep{{_name: field._originalName}}
+	}
+	return field._path
+}
+
+// pathName joins the names of the steps that are not embedded with sep.
+func pathName(steps []pathStep, sep string) string {
+	var names []string
+	for _, step := range steps {
+		if !step._embedded {
+			names = append(names, step._name)
+		}
+	}
+	if len(names) == 0 {
+		// a filtered embedded field is named by its type, like in Go
+		names = append(names, steps[len(steps)-1]._name)
+	}
+	return strings.Join(names, sep)
+}
+
+// fieldDisplayName returns the dotted path of the field, e.g. Dimensions.Weight, as reported in errors.
+func fieldDisplayName(field _field) string {
+	return pathName(fieldSteps(field), ".")
+}
+
+// fieldColumn returns the SQL column of the field, the column tag option or the snake cased field name.
+func fieldColumn(field _field) string {
+	if field._qf._column != "" {
+		return field._qf._column
+	}
+	return snakeCase(field._originalName)
+}
+
+// snakeCase converts Go names into snake case, keeping initialisms together: CreatedAt is
+// created_at, SKU is sku, HTTPStatus is http_status and CategoryIDs is category_ids.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			// the plural s of an initialism does not start a word
+			plural := i+1 < len(runes) && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]) && !plural
+			if i > 0 && (prevLower || nextLower) && runes[i-1] != '_' {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
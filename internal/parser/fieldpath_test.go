@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_snakeCase(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		"Name":          "name",
+		"CreatedAt":     "created_at",
+		"SKU":           "sku",
+		"HTTPStatus":    "http_status",
+		"LatencyMs":     "latency_ms",
+		"CategoryIDs":   "category_ids",
+		"URLsByID":      "urls_by_id",
+		"already_snake": "already_snake",
+	} {
+		require.Equal(t, want, snakeCase(input), input)
+	}
+}
+
+func Test_fieldColumn(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "created_at", fieldColumn(_field{_originalName: "CreatedAt"}))
+	require.Equal(t, "created", fieldColumn(_field{_originalName: "CreatedAt", _qf: utiQueryFilter{_column: "created"}}))
+}
+
+func Test_fieldDisplayName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Name", fieldDisplayName(_field{_originalName: "Name"}))
+	require.Equal(t, "Customer.Country", fieldDisplayName(_field{
+		_originalName: "CustomerCountry",
+		_path:         []pathStep{{_name: "Base", _embedded: true}, {_name: "Customer", _ptr: true}, {_name: "Country"}},
+	}))
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runGenerated generates the filter of structName declared in src, runs main, a main package using
+// it, and returns its output. It is skipped when the go tool or goimports is not installed.
+func runGenerated(t *testing.T, src, structName, main string, rules ..._structRule) string {
+	t.Helper()
+	for _, tool := range []string{"go", "goimports"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("go.mod", "module sample\n\ngo 1.23\n")
+	write("types.go", src)
+	write("main.go", main)
+
+	outputFile := filepath.Join(dir, "ufi_"+strings.ToLower(structName)+".go")
+	fields, err := loadStructFields(dir, outputFile, structName)
+	require.NoError(t, err)
+	code, err := GenerateCode("main", structName, fields, rules...)
+	require.NoError(t, err)
+	write(filepath.Base(outputFile), code)
+
+	out, err := exec.Command("goimports", "-w", outputFile).CombinedOutput()
+	require.NoError(t, err, string(out))
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	out, err = cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// geoPart is a part of a geo filter, each has a query key of its own.
+type geoPart string
+
+const (
+	_geoNear   geoPart = "near"
+	_geoRadius geoPart = "radius"
+	_geoBBox   geoPart = "bbox"
+)
+
+var _geoParts = []geoPart{_geoNear, _geoRadius, _geoBBox}
+
+// lonField is the longitude field paired with a geo filtered latitude field, declared next to it.
+type lonField struct {
+	_name   string
+	_column string
+}
+
+func geoPartPostfix(part geoPart) string {
+	switch part {
+	case _geoNear:
+		return "Near"
+	case _geoRadius:
+		return "Radius"
+	}
+	return "BBox"
+}
+
+// validateGeoKind checks that the geo kind is used alone on a float latitude field paired with a longitude field.
+func validateGeoKind(field _field) error {
+	if !hasKind(field, _qfKindGeo) {
+		if field._qf._lon != "" {
+			return fmt.Errorf("lon option requires the geo kind")
+		}
+		return nil
+	}
+	if len(field._qf._kindList) > 1 {
+		return fmt.Errorf("geo kind cannot be combined with other kinds")
+	}
+	if field._lon == nil {
+		return fmt.Errorf("geo kind requires the lon option naming the longitude field")
+	}
+	steps := fieldSteps(field)
+	if basic := fieldBasicType(field); field._slice || field._map || steps[len(steps)-1]._ptr || (basic != "float64" && basic != "float32") {
+		return fmt.Errorf("geo kind requires a float latitude field")
+	}
+	if len(field._qf._constraints) > 0 || field._qf._default != nil || field._qf._required {
+		return fmt.Errorf("constraints, default and required options are not supported for the geo kind")
+	}
+	return nil
+}
+
+func geoPartParseFunc(part geoPart) string {
+	switch part {
+	case _geoNear:
+		return "_filterParseGeoPoint"
+	case _geoRadius:
+		return "_filterParseDistance"
+	}
+	return "_filterParseGeoBox"
+}
+
+// generateGeoParser generates the parsing of a part of a geo filter. A radius is only accepted with a point.
+func generateGeoParser(variable string, field _field, pf parserField, qfConstKeyMap map[parserField]string) string {
+	const tmpl = `
+if q.Has($key) {
+	$keyRaw := q.Get($key)
+	if $keyParsed, err := $parseFunc($keyRaw); err != nil {
+		errs.add(&FilterError{Field: "$errField", Key: $key, Constraint: FilterConstraintType, Value: $keyRaw, Err: err})
+	} else {
+		$var.$parserField = &$keyParsed
+	}
+}
+`
+	const radiusTmpl = `
+if q.Has($key) && !q.Has($nearKey) {
+	errs.add(&FilterError{Field: "$errField", Key: $key, Constraint: FilterConstraintTogether, Limit: $limit})
+}`
+	near := parserField{_name: "_" + field._originalName + geoPartPostfix(_geoNear), _kind: _qfKindGeo, _geo: _geoNear}
+	code := tmpl
+	if pf._geo == _geoRadius {
+		code = radiusTmpl + tmpl
+	}
+	return namedReplace(code, map[string]string{
+		"$nearKey":     qfConstKeyMap[near],
+		"$limit":       strconv.Quote(queryKey(field, near) + "," + queryKey(field, pf)),
+		"$key":         qfConstKeyMap[pf],
+		"$parseFunc":   geoPartParseFunc(pf._geo),
+		"$errField":    fieldDisplayName(field),
+		"$var":         variable,
+		"$parserField": pf._name,
+	})
+}
+
+// generateGeoMismatchExpr returns an expression reporting whether the point x fails the geo filter part held by filter,
+// empty for the near point, which only filters together with a radius.
+func generateGeoMismatchExpr(structRcv string, field _field, pf parserField, filter string) string {
+	switch pf._geo {
+	case _geoRadius:
+		near := structRcv + "._" + field._originalName + geoPartPostfix(_geoNear)
+		return fmt.Sprintf("%s != nil && _filterGeoDistance(x, *%s) > *%s", near, near, filter)
+	case _geoBBox:
+		return fmt.Sprintf("!_filterInGeoBox(x, *%s)", filter)
+	}
+	return ""
+}
+
+// generateGeoWhere generates the SQL condition of a geo filter part, empty for the near point.
+func generateGeoWhere(structRcv string, field _field, pf parserField, filter string) string {
+	latColumn, lonColumn := strconv.Quote(fieldColumn(field)), strconv.Quote(field._lon._column)
+	switch pf._geo {
+	case _geoRadius:
+		near := structRcv + "._" + field._originalName + geoPartPostfix(_geoNear)
+		return fmt.Sprintf("if %s != nil && %s != nil {\n\t_filterWhereGeoRadius(w, %s, %s, *%s, *%s)\n}",
+			filter, near, latColumn, lonColumn, near, filter)
+	case _geoBBox:
+		return fmt.Sprintf("if %s != nil {\n\t_filterWhereGeoBox(w, %s, %s, *%s)\n}", filter, latColumn, lonColumn, filter)
+	}
+	return ""
+}
+
+// generateGeoSortFunc generates a method sorting items by their distance to the near point of a geo field.
+func generateGeoSortFunc(structRcv, structName, origStructName string, field _field) string {
+	const tmpl = `
+// SortBy$fieldDistance sorts items by the distance of $errField to the $pointKey point, nearest first,
+// keeping the order of equally distant items. Items behind a nil pointer go last. It does nothing
+// when the point is not set.
+func ($rcv *$structName) SortBy$fieldDistance(items []$origStruct) {
+	if $rcv.$near == nil {
+		return
+	}
+	distance := func(v *$origStruct) float64 {
+		x, ok := $valueFunc(v)
+		if !ok {
+			return math.Inf(1)
+		}
+		return _filterGeoDistance(x, *$rcv.$near)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return distance(&items[i]) < distance(&items[j])
+	})
+}
+`
+	near := parserField{_kind: _qfKindGeo, _geo: _geoNear}
+	return namedReplace(tmpl, map[string]string{
+		"$field":      field._originalName,
+		"$errField":   fieldDisplayName(field),
+		"$pointKey":   queryKey(field, near),
+		"$rcv":        structRcv,
+		"$structName": structName,
+		"$origStruct": origStructName,
+		"$near":       "_" + field._originalName + geoPartPostfix(_geoNear),
+		"$valueFunc":  fieldValueFuncName(field),
+	})
+}
+
+const filterGeoFuncs = `
+// FilterGeoPoint is the point of a geo filter, in degrees.
+type FilterGeoPoint struct {
+	Lat, Lon float64
+}
+
+// FilterGeoBox is the bounding box of a geo filter, in degrees. A box with MinLon greater than MaxLon
+// crosses the antimeridian.
+type FilterGeoBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// _filterEarthRadius is the mean radius of the Earth, in meters.
+const _filterEarthRadius = 6371008.8
+
+// _filterParseGeoPoint parses lat,lon.
+func _filterParseGeoPoint(inp string) (FilterGeoPoint, error) {
+	coords, err := _filterParseCoords(inp, 2)
+	if err != nil {
+		return FilterGeoPoint{}, err
+	}
+	return FilterGeoPoint{Lat: coords[0], Lon: coords[1]}, nil
+}
+
+// _filterParseGeoBox parses minLat,minLon,maxLat,maxLon.
+func _filterParseGeoBox(inp string) (FilterGeoBox, error) {
+	coords, err := _filterParseCoords(inp, 4)
+	if err != nil {
+		return FilterGeoBox{}, err
+	}
+	if coords[0] > coords[2] {
+		return FilterGeoBox{}, fmt.Errorf("min latitude %v is greater than max latitude %v", coords[0], coords[2])
+	}
+	return FilterGeoBox{MinLat: coords[0], MinLon: coords[1], MaxLat: coords[2], MaxLon: coords[3]}, nil
+}
+
+// _filterParseCoords parses n comma separated latitude and longitude pairs.
+func _filterParseCoords(inp string, n int) ([]float64, error) {
+	parts := strings.Split(inp, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma separated coordinates", n)
+	}
+	coords := make([]float64, 0, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		limit := 180.0
+		if i%2 == 0 {
+			limit = 90
+		}
+		if !(v >= -limit && v <= limit) {
+			return nil, fmt.Errorf("coordinate %v is out of [-%v, %v]", v, limit, limit)
+		}
+		coords = append(coords, v)
+	}
+	return coords, nil
+}
+
+// _filterParseDistance parses a distance into meters: a number followed by m, km or mi, meters without a unit.
+func _filterParseDistance(inp string) (float64, error) {
+	units := []struct {
+		suffix string
+		meters float64
+	}{{"km", 1000}, {"mi", 1609.344}, {"m", 1}}
+	num, meters := inp, 1.0
+	for _, unit := range units {
+		if n, ok := strings.CutSuffix(inp, unit.suffix); ok {
+			num, meters = n, unit.meters
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, err
+	}
+	if !(v >= 0) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid distance %q", inp)
+	}
+	return v * meters, nil
+}
+
+// _filterGeoDistance returns the haversine distance between a and b, in meters.
+func _filterGeoDistance(a, b FilterGeoPoint) float64 {
+	const rad = math.Pi / 180
+	sinLat := math.Sin((b.Lat - a.Lat) * rad / 2)
+	sinLon := math.Sin((b.Lon - a.Lon) * rad / 2)
+	h := sinLat*sinLat + math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*sinLon*sinLon
+	return 2 * _filterEarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func _filterInGeoBox(p FilterGeoPoint, b FilterGeoBox) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// _filterGeoRadiusBox returns the bounding box of the circle of radius meters around p. Longitudes
+// are unbounded, reported by false, when the circle reaches a pole or spans every meridian.
+func _filterGeoRadiusBox(p FilterGeoPoint, radius float64) (FilterGeoBox, bool) {
+	const deg = 180 / math.Pi
+	angle := radius / _filterEarthRadius
+	box := FilterGeoBox{MinLat: math.Max(-90, p.Lat-angle*deg), MaxLat: math.Min(90, p.Lat+angle*deg), MinLon: -180, MaxLon: 180}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box, false
+	}
+	sin := math.Sin(angle) / math.Cos(p.Lat/deg)
+	if angle >= math.Pi/2 || sin >= 1 {
+		return box, false
+	}
+	dLon := math.Asin(sin) * deg
+	box.MinLon, box.MaxLon = p.Lon-dLon, p.Lon+dLon
+	if box.MinLon < -180 {
+		box.MinLon += 360
+	}
+	if box.MaxLon > 180 {
+		box.MaxLon -= 360
+	}
+	return box, true
+}
+
+func _filterWhereGeoBox(w *_filterWhere, latColumn, lonColumn string, b FilterGeoBox) {
+	_filterWhereGeoLat(w, latColumn, b)
+	_filterWhereGeoLon(w, lonColumn, b)
+}
+
+// _filterWhereGeoRadius renders the bounding box of the circle, with plain comparisons that need
+// no geo extension. It holds items up to the corners of the box, Match drops them.
+func _filterWhereGeoRadius(w *_filterWhere, latColumn, lonColumn string, p FilterGeoPoint, radius float64) {
+	box, lonBounded := _filterGeoRadiusBox(p, radius)
+	_filterWhereGeoLat(w, latColumn, box)
+	if lonBounded {
+		_filterWhereGeoLon(w, lonColumn, box)
+	}
+}
+
+func _filterWhereGeoLat(w *_filterWhere, column string, b FilterGeoBox) {
+	w.conds = append(w.conds, column+" BETWEEN "+w.arg(b.MinLat)+" AND "+w.arg(b.MaxLat))
+}
+
+func _filterWhereGeoLon(w *_filterWhere, column string, b FilterGeoBox) {
+	if b.MinLon <= b.MaxLon {
+		w.conds = append(w.conds, column+" BETWEEN "+w.arg(b.MinLon)+" AND "+w.arg(b.MaxLon))
+		return
+	}
+	w.conds = append(w.conds, "("+column+" >= "+w.arg(b.MinLon)+" OR "+column+" <= "+w.arg(b.MaxLon)+")")
+}`
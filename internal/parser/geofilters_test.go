@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateGeoKind(t *testing.T) {
+	t.Parallel()
+
+	qf := utiQueryFilter{_kindList: []queryFilterKind{_qfKindGeo}, _lon: "Lon"}
+	lon := &lonField{_name: "Lon", _column: "lon"}
+	require.NoError(t, validateGeoKind(_field{_originalName: "Lat", _goType: "float64", _qf: qf, _lon: lon}))
+	require.NoError(t, validateGeoKind(_field{_originalName: "Lat", _goType: "int", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}}}))
+	require.Error(t, validateGeoKind(_field{_originalName: "Lat", _goType: "float64", _qf: qf}))
+	require.Error(t, validateGeoKind(_field{_originalName: "Lat", _goType: "string", _qf: qf, _lon: lon}))
+	require.Error(t, validateGeoKind(_field{_originalName: "Lat", _goType: "float64", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}, _lon: "Lon"}}))
+
+	combined := qf
+	combined._kindList = []queryFilterKind{_qfKindGeo, _qfKindRange}
+	require.Error(t, validateGeoKind(_field{_originalName: "Lat", _goType: "float64", _qf: combined, _lon: lon}))
+}
+
+func Test_queryKey_geo(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "near", queryKey(_field{}, parserField{_kind: _qfKindGeo, _geo: _geoNear}))
+	require.Equal(t, "store-bbox", queryKey(_field{_qf: utiQueryFilter{_key: "store"}}, parserField{_kind: _qfKindGeo, _geo: _geoBBox}))
+}
+
+func Test_generateGeoWhere(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "Lat", _qf: utiQueryFilter{_column: "lat"}, _lon: &lonField{_name: "Lon", _column: "lon"}}
+
+	// Act
+	radius := generateGeoWhere("f", field, parserField{_name: "_LatRadius", _geo: _geoRadius}, "f._LatRadius")
+	near := generateGeoWhere("f", field, parserField{_name: "_LatNear", _geo: _geoNear}, "f._LatNear")
+
+	// Assert
+	require.Equal(t, "if f._LatRadius != nil && f._LatNear != nil {\n\t_filterWhereGeoRadius(w, \"lat\", \"lon\", *f._LatNear, *f._LatRadius)\n}", radius)
+	require.Empty(t, near)
+}
+
+func TestGeoFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Place struct {\n" +
+		"\tName string\n" +
+		"\tLat  float64 `ufi:\"kind=geo;lon=Lon\"`\n" +
+		"\tLon  float64\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"math"
+)
+
+func main() {
+	for _, pair := range [][2]FilterGeoPoint{
+		{{0, 179.5}, {0, -179.5}},
+		{{51.5007, -0.1246}, {48.8584, 2.2945}},
+		{{-16.5, 179.9}, {-17.7, -178.1}},
+	} {
+		fmt.Println(math.Round(_filterGeoDistance(pair[0], pair[1])))
+	}
+
+	places := []Place{{"east", 0, 179.5}, {"west", 0, -179.5}, {"inland", 0, 178}, {"origin", 0, 0}}
+	for _, query := range []string{
+		"near=0,179.5&radius=112km",
+		"near=0,179.5&radius=111km",
+		"near=0,-179.9&radius=100mi",
+		"bbox=-1,179,1,-179",
+		"bbox=-1,-1,1,179",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			panic(err)
+		}
+		var names []string
+		for _, p := range f.Apply(places) {
+			names = append(names, p.Name)
+		}
+		where, args := f.Where(FilterDialectPostgres)
+		fmt.Println(query, names, where, len(args))
+	}
+
+	f, err := ParseFiltersQuery("near=0,-179.9&radius=500km")
+	if err != nil {
+		panic(err)
+	}
+	f.SortByLatDistance(places)
+	fmt.Println(places)
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Place", main)
+
+	// Assert
+	require.Equal(t, `111195
+340539
+250965
+near=0,179.5&radius=112km [east west] lat BETWEEN $1 AND $2 AND (lon >= $3 OR lon <= $4) 4
+near=0,179.5&radius=111km [east] lat BETWEEN $1 AND $2 AND (lon >= $3 OR lon <= $4) 4
+near=0,-179.9&radius=100mi [east west] lat BETWEEN $1 AND $2 AND (lon >= $3 OR lon <= $4) 4
+bbox=-1,179,1,-179 [east west] lat BETWEEN $1 AND $2 AND (lon >= $3 OR lon <= $4) 4
+bbox=-1,-1,1,179 [inland origin] lat BETWEEN $1 AND $2 AND lon BETWEEN $3 AND $4 4
+[{west 0 -179.5} {east 0 179.5} {inland 0 178} {origin 0 0}]
+`, got)
+}
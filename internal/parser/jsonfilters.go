@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonOperator returns the operator of pf in JSON search documents: eq, in, gte, lte, the set kind,
+// match for text, the geo part or in-cidr.
+func jsonOperator(pf parserField) string {
+	switch {
+	case pf.isRangeGte:
+		return "gte"
+	case pf.isRangeLte:
+		return "lte"
+	case pf._geo != "":
+		return string(pf._geo)
+	}
+	switch pf._kind {
+	case _qfKindExact:
+		return "eq"
+	case _qfKindMultiValue:
+		return "in"
+	case _qfKindText:
+		return "match"
+	}
+	return string(pf._kind)
+}
+
+// generateJSONKeysDef generates the tables mapping keys and operators of JSON search documents
+// to query keys.
+func generateJSONKeysDef(fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) string {
+	var keys, maps []string
+	for _, field := range fields {
+		pfs := structFieldMap[field._originalName]
+		if len(pfs) == 0 || field._qf._key == "" {
+			continue
+		}
+		if hasKind(field, _qfKindMap) {
+			maps = append(maps, fmt.Sprintf("%s: true,", strconv.Quote(field._qf._key)))
+			continue
+		}
+		ops := make([]string, 0, len(pfs))
+		for _, pf := range pfs {
+			ops = append(ops, fmt.Sprintf("%s: %s", strconv.Quote(jsonOperator(pf)), qfConstKeyMap[pf]))
+		}
+		sort.Strings(ops)
+		keys = append(keys, fmt.Sprintf("%s: {%s},", strconv.Quote(field._qf._key), strings.Join(ops, ", ")))
+	}
+	rows := []string{
+		"// _filterJSONKeys maps the keys of JSON search documents and their operators to query keys.",
+		"var _filterJSONKeys = map[string]map[string]string{",
+	}
+	rows = append(rows, keys...)
+	rows = append(rows, "}", "", "// _filterMapKeys holds the keys of map filters, their JSON values are objects of attributes.",
+		"var _filterMapKeys = map[string]bool{")
+	rows = append(rows, maps...)
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n")
+}
+
+// generateJSONParseFunc generates ParseFiltersJSON.
+func generateJSONParseFunc(structName string) string {
+	const tmpl = `
+// ParseFiltersJSON parses the filters of a JSON search document such as
+// {"skus": {"in": [1, 2]}, "price": {"gte": 10}}. Each key of a filter holds an object of operators:
+// eq, in, gte, lte, any, all, none, match, in-cidr, near, radius and bbox, as the kinds of the key allow,
+// or a value, read like the key in a query. Map filters hold an object of attributes, query keys such
+// as price-from are accepted too, other keys are rejected. Values are converted into a query, arrays
+// into comma separated lists of values without commas, and parsed with ParseFiltersValues, so both
+// give the same filters and errors. Numbers are always read in Go syntax, locales are not applied.
+func ParseFiltersJSON(data []byte, opts ...FilterOption) (*$structName, error) {
+	q, err := _filterJSONQuery(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFiltersValues(q, append(opts[:len(opts):len(opts)], func(o *filterOptions) {
+		o.goSyntax = true
+	})...)
+}
+`
+	return namedReplace(tmpl, map[string]string{"$structName": structName})
+}
+
+const filterJSONFuncs = `
+// _filterJSONQuery converts a JSON search document into query values, see ParseFiltersJSON.
+func _filterJSONQuery(data []byte) (url.Values, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON filters: %w", err)
+	}
+	q := make(url.Values)
+	set := func(key string, v any) error {
+		if v == nil {
+			return nil
+		}
+		if q.Has(key) {
+			return fmt.Errorf("filter %q is set twice", key)
+		}
+		s, err := _filterJSONValue(key, v)
+		if err != nil {
+			return err
+		}
+		q.Set(key, s)
+		return nil
+	}
+	for key, v := range doc {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			if !_filterJSONQueryKey(key) {
+				if _, ok := _filterJSONKeys[key]; ok {
+					return nil, fmt.Errorf("filter %q: expected an object of operators", key)
+				}
+				return nil, fmt.Errorf("unknown filter %q", key)
+			}
+			if err := set(key, v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _filterMapKeys[key] {
+			for name, attr := range obj {
+				if err := set(key+"["+name+"]", attr); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		ops, ok := _filterJSONKeys[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", key)
+		}
+		for op, opValue := range obj {
+			queryKey, ok := ops[op]
+			if !ok {
+				return nil, fmt.Errorf("filter %q: unknown operator %q", key, op)
+			}
+			if err := set(queryKey, opValue); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return q, nil
+}
+
+// _filterJSONQueryKey reports whether key is a query key, such as price-from or attr[color], or the key of a map filter.
+func _filterJSONQueryKey(key string) bool {
+	name, attr, isAttr := strings.Cut(key, "[")
+	return _filterQueryKeys[key] || _filterMapKeys[key] || isAttr && _filterMapKeys[name] && strings.HasSuffix(attr, "]")
+}
+
+// _filterJSONValue converts a JSON value into a query value. Numbers keep their text, so decimals are exact.
+// Values of arrays cannot hold commas, they would be read as several values.
+func _filterJSONValue(key string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := _filterJSONValue(key, e)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(s, ",") {
+				return "", fmt.Errorf("filter %q: list value %q holds the separator \",\"", key, s)
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	}
+	return "", fmt.Errorf("filter %q: unexpected JSON value %v", key, v)
+}
+`
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateJSONKeysDef(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "SKU",
+		_goType:       "uint64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange, _qfKindMultiValue, _qfKindExact}, _key: "skus"},
+	}, {
+		_originalName: "Attributes",
+		_goType:       "string",
+		_map:          true,
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindMap}, _key: "attr"},
+	}}
+	_, fieldMap := generateFilterStructDef("_ProductFilter", fields)
+	_, constMap := generateConstKeys(fields, fieldMap)
+
+	// Act
+	got := generateJSONKeysDef(fields, fieldMap, constMap)
+
+	// Assert
+	require.Contains(t, got, `"skus": {"eq": _SKUKey, "gte": _SKUKey_gte, "in": _SKUKey, "lte": _SKUKey_lte},`)
+	require.Contains(t, got, "var _filterMapKeys = map[string]bool{\n\"attr\": true,\n}")
+}
+
+func Test_jsonOperator(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "gte", jsonOperator(parserField{_kind: _qfKindRange, isRangeGte: true}))
+	require.Equal(t, "in", jsonOperator(parserField{_kind: _qfKindMultiValue}))
+	require.Equal(t, "none", jsonOperator(parserField{_kind: _qfKindNone}))
+	require.Equal(t, "radius", jsonOperator(parserField{_kind: _qfKindGeo, _geo: _geoRadius}))
+	require.Equal(t, "in-cidr", jsonOperator(parserField{_kind: _qfKindInCIDR}))
+}
+
+func TestJSONFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tName string `ufi:\"kind=exact,multi-value;key=name\"`\n" +
+		"\tPrice float64 `ufi:\"kind=range;key=price;max=1000\"`\n" +
+		"\tTags []string `ufi:\"kind=any;key=tags\"`\n" +
+		"\tAttributes map[string]string `ufi:\"kind=map;key=attr;attrs=color\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+func main() {
+	show := func(f *_ProductFilter, err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		where, args := f.Where(FilterDialectPostgres)
+		return fmt.Sprint(where, " ", args)
+	}
+	for _, test := range []struct{ doc, query string }{
+		{` + "`" + `{"name": {"in": ["a", "b"]}, "price": {"gte": 10, "lte": 20.5}}` + "`" + `, "name=a,b&price-from=10&price-to=20.5"},
+		{` + "`" + `{"name": "a", "price-to": 5}` + "`" + `, "name=a&price-to=5"},
+		{` + "`" + `{"tags": {"any": ["x", "y"]}, "attr": {"color": "red"}}` + "`" + `, "tags-any=x,y&attr[color]=red"},
+		{` + "`" + `{"attr[color]": "red"}` + "`" + `, "attr[color]=red"},
+		{` + "`" + `{"price": {"gte": 2000}}` + "`" + `, "price-from=2000"},
+		{` + "`" + `{"price": {"gte": 30, "lte": 20}}` + "`" + `, "price-from=30&price-to=20"},
+		{` + "`" + `{"attr": "red"}` + "`" + `, "attr=red"},
+	} {
+		q, err := url.ParseQuery(test.query)
+		if err != nil {
+			panic(err)
+		}
+		fromJSON := show(ParseFiltersJSON([]byte(test.doc)))
+		fromQuery := show(ParseFiltersValues(q))
+		fmt.Println(fromJSON == fromQuery, fromJSON)
+	}
+	for _, doc := range []string{
+		` + "`" + `{"name": "a", "foo": 1}` + "`" + `,
+		` + "`" + `{"foo": {"eq": 1}}` + "`" + `,
+		` + "`" + `{"price": 10}` + "`" + `,
+		` + "`" + `{"name": {"in": ["a,b"]}}` + "`" + `,
+		` + "`" + `{"name": {"like": "a"}}` + "`" + `,
+	} {
+		fmt.Println(show(ParseFiltersJSON([]byte(doc))))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `true name IN ($1, $2) AND price <= $3 AND price >= $4 [a b 20.5 10]
+true name = $1 AND price <= $2 [a 5]
+true tags && $1 AND (attributes->>$2) = $3 [[x y] color red]
+true (attributes->>$1) = $2 [color red]
+true filter "price-from" (field Price): value "2000" violates max=1000
+true filter "price-from" (field Price): lower bound "30" is greater than upper bound "20"
+true filter "attr" (field Attributes): invalid value "red": expected attr[name]
+unknown filter "foo"
+unknown filter "foo"
+filter "price": expected an object of operators
+filter "name": list value "a,b" holds the separator ","
+filter "name": unknown operator "like"
+`, got)
+}
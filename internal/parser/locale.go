@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// filterLocaleVars maps names of the locale tag option to the generated locales.
+var filterLocaleVars = map[string]string{
+	"en": "FilterLocaleEN",
+	"de": "FilterLocaleDE",
+	"fr": "FilterLocaleFR",
+	"ru": "FilterLocaleRU",
+	"ch": "FilterLocaleCH",
+}
+
+// isLocalized reports whether the field is parsed as a number that may be written in a locale.
+func isLocalized(field _field) bool {
+	if vp, _ := resolveValueParser(field); vp != _valueParserBuiltin {
+		return false
+	}
+	class := classifyGoType(fieldBasicType(field))
+	if !field._qf._decimal && class != _goTypeClassInt && class != _goTypeClassUint && class != _goTypeClassFloat {
+		return false
+	}
+	return slices.ContainsFunc(field._qf._kindList, func(kind queryFilterKind) bool {
+		return kind != _qfKindGeo && kind != _qfKindMap
+	})
+}
+
+// validateLocale checks the locale option, allowed on fields parsed as numbers.
+func validateLocale(field _field) error {
+	if field._qf._locale == "" {
+		return nil
+	}
+	if _, ok := filterLocaleVars[field._qf._locale]; !ok {
+		names := make([]string, 0, len(filterLocaleVars))
+		for name := range filterLocaleVars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown locale %q, expected one of %s", field._qf._locale, strings.Join(names, ", "))
+	}
+	if !isLocalized(field) {
+		return fmt.Errorf("locale option requires a number or decimal field")
+	}
+	return nil
+}
+
+// generateLocaleExpr returns an expression of the locale values of pf are written in, nil for Go syntax.
+// Defaults are always written in Go syntax.
+func generateLocaleExpr(field _field, pf parserField, qfKeyConstName string) string {
+	loc := "o.numberLocale(nil)"
+	if field._qf._locale != "" {
+		loc = fmt.Sprintf("o.numberLocale(&%s)", filterLocaleVars[field._qf._locale])
+	}
+	if parserFieldDefault(field, pf) != nil {
+		loc = fmt.Sprintf("_filterQueryLocale(res._defaulted, %s, %s)", qfKeyConstName, loc)
+	}
+	return loc
+}
+
+// generateLocaleParseCall generates an expression parsing rawVar written in the locale of pf with parseFunc.
+func generateLocaleParseCall(field _field, pf parserField, qfKeyConstName, rawVar, parseFunc string) string {
+	loc := generateLocaleExpr(field, pf, qfKeyConstName)
+	if isListKind(pf._kind) {
+		return fmt.Sprintf("glocalesliceparse(%s, %s, %s)", rawVar, loc, parseFunc)
+	}
+	return fmt.Sprintf("glocaleparse(%s, %s, %s)", rawVar, loc, parseFunc)
+}
+
+const filterLocaleDef = `
+// FilterLocale describes how users write numbers, e.g. 1 299,50. Digits of the whole part may be
+// grouped by three with one of the Group runes, Decimal separates the fraction.
+type FilterLocale struct {
+	Decimal rune
+	Group   string
+}
+
+var (
+	// FilterLocaleEN reads 1,299.50.
+	FilterLocaleEN = FilterLocale{Decimal: '.', Group: ","}
+	// FilterLocaleDE reads 1.299,50.
+	FilterLocaleDE = FilterLocale{Decimal: ',', Group: "."}
+	// FilterLocaleFR reads 1 299,50, grouped with spaces, no-break spaces or narrow no-break spaces.
+	FilterLocaleFR = FilterLocale{Decimal: ',', Group: " \u00a0\u202f"}
+	// FilterLocaleRU reads 1 299,50, like FilterLocaleFR.
+	FilterLocaleRU = FilterLocaleFR
+	// FilterLocaleCH reads 1'299.50.
+	FilterLocaleCH = FilterLocale{Decimal: '.', Group: "'’"}
+)
+
+// WithFilterLocale sets the locale numbers are written in, Go syntax by default. Lists of numbers
+// are separated by | rather than commas in locales with a decimal comma. Fields with the locale tag
+// option always use their own locale.
+func WithFilterLocale(l FilterLocale) FilterOption {
+	return func(o *filterOptions) {
+		o.locale = &l
+	}
+}
+`
+
+const filterLocaleFuncs = `
+// glocaleparse rewrites inp from loc into Go syntax and parses it, loc is nil for values in Go syntax.
+func glocaleparse[T any](inp string, loc *FilterLocale, parse func(string) (T, error)) (T, error) {
+	if loc != nil {
+		v, err := _filterLocaleNumber(inp, *loc)
+		if err != nil {
+			return *new(T), err
+		}
+		inp = v
+	}
+	return parse(inp)
+}
+
+// glocalesliceparse parses a list of numbers written in loc, separated by _filterListSep.
+func glocalesliceparse[T any](inp string, loc *FilterLocale, parse func(string) (T, error)) ([]T, error) {
+	splitted := strings.Split(inp, _filterListSep(loc))
+	result := make([]T, 0, len(splitted))
+	for _, v := range splitted {
+		parsed, err := glocaleparse(v, loc, parse)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+// _filterListSep returns the separator of list values: | for numbers written in a locale with a decimal
+// comma, commas otherwise. Values of comma separated lists are not grouped by commas, 1,299 is two values.
+func _filterListSep(loc *FilterLocale) string {
+	if loc != nil && loc.Decimal == ',' {
+		return "|"
+	}
+	return ","
+}
+
+// _filterQueryLocale returns loc unless the value of key is a default, defaults are written in Go syntax.
+func _filterQueryLocale(defaulted map[string]bool, key string, loc *FilterLocale) *FilterLocale {
+	if defaulted[key] {
+		return nil
+	}
+	return loc
+}
+
+// _filterLocaleNumber rewrites the number at the start of inp from l into Go syntax and keeps the rest,
+// such as a unit. Numbers mixing formats, e.g. 1.299,50 for FilterLocaleEN, or grouped by other than
+// three digits, e.g. 1,5 for FilterLocaleEN, are rejected rather than read in another way.
+func _filterLocaleNumber(inp string, l FilterLocale) (string, error) {
+	s := strings.TrimSpace(inp)
+	var b strings.Builder
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		b.WriteByte(s[0])
+		s = s[1:]
+	}
+	var group rune
+	var rest string
+	digits, grouped, fraction := 0, false, false
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+			digits++
+			continue
+		case r == l.Decimal:
+			if fraction {
+				return "", fmt.Errorf("%q has more than one decimal separator %q", inp, r)
+			}
+			if grouped && digits != 3 {
+				return "", fmt.Errorf("%q has a misplaced group separator %q, digits are grouped by three", inp, group)
+			}
+			b.WriteByte('.')
+			fraction, digits = true, 0
+			continue
+		case strings.ContainsRune(l.Group, r):
+			if fraction {
+				return "", fmt.Errorf("%q has a group separator %q after the decimal separator %q", inp, r, l.Decimal)
+			}
+			if grouped && r != group {
+				return "", fmt.Errorf("%q mixes group separators %q and %q", inp, group, r)
+			}
+			if digits == 0 || digits > 3 || grouped && digits != 3 {
+				return "", fmt.Errorf("%q has a misplaced group separator %q, digits are grouped by three", inp, r)
+			}
+			group, grouped, digits = r, true, 0
+			continue
+		case strings.ContainsRune(".,' \u00a0\u202f’", r):
+			return "", fmt.Errorf("%q has a separator %q the locale does not use", inp, r)
+		}
+		rest = s[i:]
+		break
+	}
+	if grouped && !fraction && digits != 3 {
+		return "", fmt.Errorf("%q has a misplaced group separator %q, digits are grouped by three", inp, group)
+	}
+	return b.String() + rest, nil
+}
+`
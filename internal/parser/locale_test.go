@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateLocale(t *testing.T) {
+	t.Parallel()
+
+	range_ := []queryFilterKind{_qfKindRange}
+	require.NoError(t, validateLocale(_field{_goType: "float64", _qf: utiQueryFilter{_kindList: range_, _locale: "ru"}}))
+	require.NoError(t, validateLocale(_field{_goType: "string", _qf: utiQueryFilter{_kindList: range_, _decimal: true, _locale: "de"}}))
+	require.Error(t, validateLocale(_field{_goType: "float64", _qf: utiQueryFilter{_kindList: range_, _locale: "xx"}}))
+	require.Error(t, validateLocale(_field{_goType: "string", _qf: utiQueryFilter{_kindList: range_, _locale: "en"}}))
+	require.Error(t, validateLocale(_field{_goType: "time.Duration", _qf: utiQueryFilter{_kindList: range_, _locale: "en"}}))
+	require.Error(t, validateLocale(_field{_goType: "float64", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindGeo}, _locale: "en"}}))
+}
+
+func Test_generateParseCall_locale(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "Price", _goType: "float64", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange, _qfKindMultiValue}}}
+	gte := parserField{_name: "_PriceGte", _kind: _qfKindRange, isRangeGte: true}
+	multi := parserField{_name: "_PriceMultiValue", _kind: _qfKindMultiValue}
+
+	// Act
+	got := generateParseCall(field, gte, "_PriceKey_gte")
+
+	// Assert
+	require.Equal(t, "glocaleparse(_PriceKey_gteRaw, o.numberLocale(nil), gfloatparse[float64])", got)
+	require.Equal(t, "glocalesliceparse(_PriceKeyRaw, o.numberLocale(nil), gfloatparse[float64])", generateParseCall(field, multi, "_PriceKey"))
+
+	def := "1.5"
+	field._qf._locale = "de"
+	field._qf._defaultFrom = &def
+	require.Equal(t, "glocaleparse(_PriceKey_gteRaw, _filterQueryLocale(res._defaulted, _PriceKey_gte, o.numberLocale(&FilterLocaleDE)), gfloatparse[float64])",
+		generateParseCall(field, gte, "_PriceKey_gte"))
+}
+
+func TestLocaleNumbers(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tSKU uint64 `ufi:\"kind=multi-value,exact;key=skus\"`\n" +
+		"\tPrice float64 `ufi:\"kind=range,multi-value;key=price\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	for _, test := range []struct {
+		loc FilterLocale
+		inp string
+	}{
+		{FilterLocaleEN, "1,299.50"},
+		{FilterLocaleEN, "-1,299"},
+		{FilterLocaleDE, "1.299,50"},
+		{FilterLocaleFR, "1 299,50"},
+		{FilterLocaleFR, "1\u00a0299,5kg"},
+		{FilterLocaleCH, "1'299.50"},
+		{FilterLocaleEN, "1.299,50"},
+		{FilterLocaleEN, "1,5"},
+		{FilterLocaleEN, "12,34,567"},
+		{FilterLocaleDE, "1,2,3"},
+		{FilterLocaleFR, "1 299\u00a0000"},
+		{FilterLocaleCH, "1,299"},
+	} {
+		v, err := _filterLocaleNumber(test.inp, test.loc)
+		fmt.Printf("%q %q %v\n", test.inp, v, err)
+	}
+	for _, test := range []struct {
+		loc *FilterLocale
+		inp string
+	}{
+		{nil, "1,2"},
+		{&FilterLocaleEN, "1,299"},
+		{&FilterLocaleCH, "1'299,5"},
+		{&FilterLocaleDE, "1,5|2.000,25"},
+		{&FilterLocaleRU, "1 000|2,5"},
+		{&FilterLocaleDE, "1,5,2"},
+	} {
+		v, err := glocalesliceparse(test.inp, test.loc, gfloatparse[float64])
+		fmt.Println(test.inp, v, err)
+	}
+	for _, query := range []string{"skus=1,2", "skus=1,299", "skus=1299", "price=1.5,2"} {
+		f, err := ParseFiltersQuery(query, WithFilterLocale(FilterLocaleEN))
+		fmt.Println(query, f.GetSKUExact(), f.GetSKUArray(), f.GetPriceArray(), err)
+	}
+	for _, query := range []string{"skus=1.299|5", "price=1,5|2", "price=1,5"} {
+		f, err := ParseFiltersQuery(query, WithFilterLocale(FilterLocaleDE))
+		fmt.Println(query, f.GetSKUArray(), f.GetPriceArray(), err)
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `"1,299.50" "1299.50" <nil>
+"-1,299" "-1299" <nil>
+"1.299,50" "1299.50" <nil>
+"1 299,50" "1299.50" <nil>
+"1\u00a0299,5kg" "1299.5kg" <nil>
+"1'299.50" "1299.50" <nil>
+"1.299,50" "" "1.299,50" has a group separator ',' after the decimal separator '.'
+"1,5" "" "1,5" has a misplaced group separator ',', digits are grouped by three
+"12,34,567" "" "12,34,567" has a misplaced group separator ',', digits are grouped by three
+"1,2,3" "" "1,2,3" has more than one decimal separator ','
+"1 299\u00a0000" "" "1 299\u00a0000" mixes group separators ' ' and '\u00a0'
+"1,299" "" "1,299" has a separator ',' the locale does not use
+1,2 [1 2] <nil>
+1,299 [1 299] <nil>
+1'299,5 [1299 5] <nil>
+1,5|2.000,25 [1.5 2000.25] <nil>
+1 000|2,5 [1000 2.5] <nil>
+1,5,2 [] "1,5,2" has more than one decimal separator ','
+skus=1,2 0 [1 2] [] <nil>
+skus=1,299 0 [1 299] [] <nil>
+skus=1299 1299 [1299] [] <nil>
+price=1.5,2 0 [] [1.5 2] <nil>
+skus=1.299|5 [1299 5] [] <nil>
+price=1,5|2 [] [1.5 2] <nil>
+price=1,5 [] [1.5] <nil>
+`, got)
+}
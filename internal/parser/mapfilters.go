@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// mapAttr is an entry of the attrs tag option: an allowed attribute name and, optionally,
+// the type its values are checked and compared as.
+type mapAttr struct {
+	_name string
+	_type string
+}
+
+var _mapAttrTypes = map[string]bool{"int": true, "float": true, "bool": true}
+
+// parseMapAttrs parses the attrs tag option, e.g. color,size:int,weight:float.
+func parseMapAttrs(s string) []mapAttr {
+	var attrs []mapAttr
+	for _, entry := range strings.Split(s, ",") {
+		name, typ, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if name != "" {
+			attrs = append(attrs, mapAttr{_name: name, _type: typ})
+		}
+	}
+	return attrs
+}
+
+// validateMapKind checks that the map kind, and only it, is used on map fields.
+func validateMapKind(field _field) error {
+	for _, kind := range field._qf._kindList {
+		switch {
+		case field._map && kind != _qfKindMap:
+			return fmt.Errorf("%s kind is not supported for map fields, use map", kind)
+		case !field._map && kind == _qfKindMap:
+			return fmt.Errorf("map kind requires a map[string]T field")
+		}
+	}
+	if !field._map {
+		if len(field._qf._attrs) > 0 {
+			return fmt.Errorf("attrs option requires the map kind")
+		}
+		return nil
+	}
+	if field._goType == "time.Time" {
+		return fmt.Errorf("map kind is not supported for time values")
+	}
+	if field._qf._default != nil || field._qf._required {
+		return fmt.Errorf("default and required options are not supported for the map kind")
+	}
+	for _, attr := range field._qf._attrs {
+		if attr._type == "" {
+			continue
+		}
+		if !_mapAttrTypes[attr._type] {
+			return fmt.Errorf("attribute %s: unknown type %q, expected int, float or bool", attr._name, attr._type)
+		}
+		if field._goType != "string" {
+			return fmt.Errorf("attribute %s: typed attributes require map[string]string", attr._name)
+		}
+	}
+	return nil
+}
+
+// parserFieldType returns the type of the value pf holds.
+func parserFieldType(field _field, pf parserField) string {
+	switch {
+	case isListKind(pf._kind):
+		return "[]" + field._goType
+	case pf._kind == _qfKindMap:
+		return "map[string]" + field._goType
+	case pf._kind == _qfKindText:
+		return "[]string"
+	case pf._geo == _geoNear:
+		return "FilterGeoPoint"
+	case pf._geo == _geoRadius:
+		return "float64"
+	case pf._geo == _geoBBox:
+		return "FilterGeoBox"
+	case pf._kind == _qfKindInCIDR:
+		return "[]netip.Prefix"
+	}
+	return field._goType
+}
+
+func mapAttrsVarName(field _field) string {
+	return "_" + field._originalName + "Attrs"
+}
+
+// generateMapAttrsVar generates the allowed attributes of the field by name, with their type.
+// It is nil when every attribute is allowed.
+func generateMapAttrsVar(field _field) string {
+	if len(field._qf._attrs) == 0 {
+		return fmt.Sprintf("var %s map[string]string", mapAttrsVarName(field))
+	}
+	rows := []string{fmt.Sprintf("var %s = map[string]string{", mapAttrsVarName(field))}
+	for _, attr := range field._qf._attrs {
+		rows = append(rows, fmt.Sprintf("%s: %s,", strconv.Quote(attr._name), strconv.Quote(attr._type)))
+	}
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n")
+}
+
+// generateMapParser generates the parsing of every key[name] query key of a map field.
+func generateMapParser(variable string, field _field, pf parserField, qfKeyConstName string) string {
+	const tmpl = `
+if m, ferrs := _filterParseAttrs(q, $key, "$errField", $attrs, $parseFunc, $validate); len(ferrs) > 0 {
+	for _, fe := range ferrs {
+		errs.add(fe)
+	}
+} else if m != nil {
+	$var.$parserField = &m
+}
+`
+	vp, _ := resolveValueParser(field)
+	validate := "nil"
+	if len(fieldConstraints(field)) > 0 {
+		validate = validatorFuncName(field)
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$key":         qfKeyConstName,
+		"$errField":    fieldDisplayName(field),
+		"$attrs":       mapAttrsVarName(field),
+		"$parseFunc":   generateParseFunc(field, vp),
+		"$validate":    validate,
+		"$var":         variable,
+		"$parserField": pf._name,
+	})
+}
+
+// generateMapJSONType returns the FilterJSONType values of the field are compared as in SQL.
+func generateMapJSONType(field _field) string {
+	switch class := classifyGoType(fieldBasicType(field)); {
+	case isNumericClass(class):
+		return "FilterJSONNumber"
+	case fieldBasicType(field) == "bool":
+		return "FilterJSONBool"
+	}
+	return "FilterJSONText"
+}
+
+const filterMapFuncs = `
+// _filterParseAttrs parses the values of the key[name] query keys. With allowed, only the names
+// it holds are accepted and values of typed names must parse as their type. The key without a
+// name and names sent more than once are reported, since they name no single attribute value.
+func _filterParseAttrs[V any](q url.Values, key, field string, allowed map[string]string, parse func(string) (V, error), validate func(string, V) *FilterError) (map[string]V, FilterErrors) {
+	queryKeys := make([]string, 0, len(q))
+	for queryKey := range q {
+		queryKeys = append(queryKeys, queryKey)
+	}
+	sort.Strings(queryKeys)
+
+	var res map[string]V
+	var errs FilterErrors
+	if q.Has(key) {
+		errs.add(&FilterError{Field: field, Key: key, Constraint: FilterConstraintType, Value: q.Get(key), Err: fmt.Errorf("expected %s[name]", key)})
+	}
+	for _, queryKey := range queryKeys {
+		name, ok := strings.CutPrefix(queryKey, key+"[")
+		if !ok || !strings.HasSuffix(name, "]") {
+			continue
+		}
+		name = strings.TrimSuffix(name, "]")
+		raw := q.Get(queryKey)
+		if n := len(q[queryKey]); n > 1 {
+			errs.add(&FilterError{Field: field, Key: queryKey, Constraint: FilterConstraintType, Value: raw, Err: fmt.Errorf("sent %d times, expected one value", n)})
+			continue
+		}
+		typ, known := allowed[name]
+		if allowed != nil && !known {
+			names := make([]string, 0, len(allowed))
+			for allowedName := range allowed {
+				names = append(names, allowedName)
+			}
+			sort.Strings(names)
+			errs.add(&FilterError{Field: field, Key: queryKey, Constraint: FilterConstraintAttr, Limit: strings.Join(names, ","), Value: name})
+			continue
+		}
+		if _, err := _filterAttrValue(typ, raw); err != nil {
+			errs.add(&FilterError{Field: field, Key: queryKey, Constraint: FilterConstraintType, Value: raw, Err: err})
+			continue
+		}
+		v, err := parse(raw)
+		if err != nil {
+			errs.add(&FilterError{Field: field, Key: queryKey, Constraint: FilterConstraintType, Value: raw, Err: err})
+			continue
+		}
+		if validate != nil {
+			if ferr := validate(queryKey, v); ferr != nil {
+				errs.add(ferr)
+				continue
+			}
+		}
+		if res == nil {
+			res = make(map[string]V)
+		}
+		res[name] = v
+	}
+	return res, errs
+}
+
+// _filterAttrValue parses raw as an attribute of typ: int, float, bool or, when empty, kept as is.
+func _filterAttrValue(typ, raw string) (any, error) {
+	switch typ {
+	case "int":
+		return strconv.ParseInt(raw, 10, 64)
+	case "float":
+		return strconv.ParseFloat(raw, 64)
+	case "bool":
+		return strconv.ParseBool(raw)
+	}
+	return raw, nil
+}
+
+// _filterMatchAttrs reports whether m holds every attribute of want. Typed attributes are
+// compared as their type, so 1.50 equals 1.5 for a float.
+func _filterMatchAttrs[V any](m, want map[string]V, types map[string]string, equal func(a, b V) bool) bool {
+	for name, w := range want {
+		v, ok := m[name]
+		if !ok {
+			return false
+		}
+		if typ := types[name]; typ != "" {
+			got, err := _filterAttrValue(typ, fmt.Sprint(v))
+			wanted, _ := _filterAttrValue(typ, fmt.Sprint(w))
+			if err != nil || got != wanted {
+				return false
+			}
+			continue
+		}
+		if !equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func _filterWhereAttrs[V any](w *_filterWhere, column string, want map[string]V, types map[string]string, jsonType FilterJSONType) {
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		typ, arg := jsonType, any(want[name])
+		if types[name] != "" {
+			typ = FilterJSONNumber
+			if types[name] == "bool" {
+				typ = FilterJSONBool
+			}
+			arg, _ = _filterAttrValue(types[name], fmt.Sprint(want[name]))
+		}
+		w.conds = append(w.conds, w.dialect.JSONField(column, name, typ, w.arg)+" = "+w.arg(arg))
+	}
+}`
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseMapAttrs(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got := parseMapAttrs("color, size:int,,weight:float")
+
+	// Assert
+	require.Equal(t, []mapAttr{{_name: "color"}, {_name: "size", _type: "int"}, {_name: "weight", _type: "float"}}, got)
+}
+
+func Test_validateMapKind(t *testing.T) {
+	t.Parallel()
+
+	qf := func(attrs string, kinds ...queryFilterKind) utiQueryFilter {
+		return utiQueryFilter{_kindList: kinds, _attrs: parseMapAttrs(attrs)}
+	}
+	require.NoError(t, validateMapKind(_field{_goType: "string", _map: true, _qf: qf("color,size:int", _qfKindMap)}))
+	require.NoError(t, validateMapKind(_field{_goType: "int", _map: true, _qf: qf("", _qfKindMap)}))
+	require.NoError(t, validateMapKind(_field{_goType: "string", _qf: qf("", _qfKindExact)}))
+	require.Error(t, validateMapKind(_field{_goType: "string", _map: true, _qf: qf("", _qfKindExact)}))
+	require.Error(t, validateMapKind(_field{_goType: "string", _qf: qf("", _qfKindMap)}))
+	require.Error(t, validateMapKind(_field{_goType: "string", _qf: qf("color", _qfKindExact)}))
+	require.Error(t, validateMapKind(_field{_goType: "string", _map: true, _qf: qf("size:uuid", _qfKindMap)}))
+	require.Error(t, validateMapKind(_field{_goType: "int", _map: true, _qf: qf("size:int", _qfKindMap)}))
+
+	required := qf("", _qfKindMap)
+	required._required = true
+	require.Error(t, validateMapKind(_field{_goType: "string", _map: true, _qf: required}))
+}
+
+func Test_generateMapAttrsVar(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "Attributes", _qf: utiQueryFilter{_attrs: parseMapAttrs("color,weight:float")}}
+	require.Equal(t, "var _AttributesAttrs = map[string]string{\n\"color\": \"\",\n\"weight\": \"float\",\n}", generateMapAttrsVar(field))
+	require.Equal(t, "var _AttributesAttrs map[string]string", generateMapAttrsVar(_field{_originalName: "Attributes"}))
+}
+
+func Test_generateMapJSONType(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "FilterJSONText", generateMapJSONType(_field{_goType: "string", _map: true}))
+	require.Equal(t, "FilterJSONNumber", generateMapJSONType(_field{_goType: "float64", _map: true}))
+	require.Equal(t, "FilterJSONBool", generateMapJSONType(_field{_goType: "bool", _map: true}))
+}
+
+func TestMapFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tAttributes map[string]string `ufi:\"kind=map;key=attr;attrs=color,weight:float\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	item := Product{Attributes: map[string]string{"color": "red", "weight": "1.50"}}
+	for _, query := range []string{
+		"attr[color]=red&attr[weight]=1.5",
+		"attr[color]=blue",
+		"attr=red",
+		"attr[color]=red&attr[color]=blue",
+		"attr[size]=xl",
+		"attr[weight]=heavy",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(query, f.Match(&item))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `attr[color]=red&attr[weight]=1.5 true
+attr[color]=blue false
+filter "attr" (field Attributes): invalid value "red": expected attr[name]
+filter "attr[color]" (field Attributes): invalid value "red": sent 2 times, expected one value
+filter "attr[size]" (field Attributes): unknown attribute "size", allowed: color,weight
+filter "attr[weight]" (field Attributes): invalid value "heavy": strconv.ParseFloat: parsing "heavy": invalid syntax
+`, got)
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+func fieldValueFuncName(field _field) string {
+	return "_" + field._originalName + "Value"
+}
+
+// generateFieldValueFunc generates a function reading the field from the filtered struct.
+// It walks the path of the field and reports false when a pointer on the way is nil.
+func generateFieldValueFunc(origStructName string, field _field) string {
+	const tmpl = `
+func $valueFunc(v *$struct) ($goType, bool) {
+	$nilChecks
+	return $expr, true
+}
+`
+	steps := fieldSteps(field)
+	var nilChecks []string
+	expr := "v"
+	for _, step := range steps {
+		expr += "." + step._name
+		if step._ptr {
+			nilChecks = append(nilChecks, fmt.Sprintf("if %s == nil {\n\treturn *new(%s), false\n}", expr, fieldValueType(field)))
+		}
+	}
+	switch {
+	case field._lon != nil:
+		parent := strings.TrimSuffix(expr, "."+steps[len(steps)-1]._name)
+		expr = fmt.Sprintf("FilterGeoPoint{Lat: float64(%s), Lon: float64(%s.%s)}", expr, parent, field._lon._name)
+	case steps[len(steps)-1]._ptr:
+		expr = "*" + expr
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$valueFunc": fieldValueFuncName(field),
+		"$struct":    origStructName,
+		"$goType":    fieldValueType(field),
+		"$nilChecks": strings.Join(nilChecks, "\n"),
+		"$expr":      expr,
+	})
+}
+
+// generateEqualExpr returns an expression reporting whether a equals b.
+func generateEqualExpr(field _field, a, b string) string {
+	goType := fieldBasicType(field)
+	switch {
+	case field._qf._decimal:
+		return fmt.Sprintf("_filterDecimalCmp(%s, %s) == 0", fieldText(field, a), fieldText(field, b))
+	case goType == "time.Time":
+		return fmt.Sprintf("%s.Equal(%s)", strings.TrimPrefix(a, "*"), b)
+	case isNumericClass(classifyGoType(goType)), goType == "string", goType == "bool":
+		return fmt.Sprintf("%s == %s", a, b)
+	case field._type != nil && field._type._comparer:
+		return fmt.Sprintf("%s.Compare(%s) == 0", strings.TrimPrefix(a, "*"), b)
+	}
+	return fmt.Sprintf("%s == %s", a, b)
+}
+
+// generateMatchFunc generates Match, reporting whether a value of the filtered struct passes
+// the filters that are set, and Apply, filtering a slice with it.
+func generateMatchFunc(structRcv, structName, origStructName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// Match reports whether v passes every filter that is set. Filters of fields behind a nil pointer never pass.
+func ($rcv *$structName) Match(v *$origStruct) bool {
+	$blocks
+	return true
+}
+
+// Apply returns the items that pass the filters, see Match.
+func ($rcv *$structName) Apply(items []$origStruct) []$origStruct {
+	var res []$origStruct
+	for i := range items {
+		if $rcv.Match(&items[i]) {
+			res = append(res, items[i])
+		}
+	}
+	return res
+}
+`
+	var blocks []string
+	for _, field := range fields {
+		if block := generateMatchBlock(structRcv, field, structFieldMap[field._originalName]); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$rcv":        structRcv,
+		"$structName": structName,
+		"$origStruct": origStructName,
+		"$blocks":     strings.Join(blocks, "\n"),
+	})
+}
+
+// generateMatchBlock generates the checks of the filters of the field, returning false when v fails
+// one of them. It is empty when the field is not filtered.
+func generateMatchBlock(structRcv string, field _field, pfs []parserField) string {
+	const blockTmpl = `if $anySet {
+	x, ok := $valueFunc(v)
+	if !ok {
+		return false
+	}
+	$checks
+}`
+	if len(pfs) == 0 {
+		return ""
+	}
+	var anySet, checks []string
+	for _, pf := range pfs {
+		filter := structRcv + "." + pf._name
+		if pf._geo == _geoNear {
+			// the point only filters with a radius
+			continue
+		}
+		anySet = append(anySet, filter+" != nil")
+
+		var mismatch string
+		switch {
+		case pf.isRangeGte:
+			mismatch, _ = generateLessExpr(field, "x", "*"+filter)
+		case pf.isRangeLte:
+			mismatch, _ = generateLessExpr(field, "*"+filter, "x")
+		case isSetKind(pf._kind):
+			mismatch = generateSetMismatchExpr(field, pf._kind, "*"+filter)
+		case pf._kind == _qfKindMap:
+			mismatch = fmt.Sprintf("!_filterMatchAttrs(x, *%s, %s, func(a, b %s) bool { return %s })",
+				filter, mapAttrsVarName(field), field._goType, generateEqualExpr(field, "a", "b"))
+		case pf._kind == _qfKindGeo:
+			mismatch = generateGeoMismatchExpr(structRcv, field, pf, filter)
+		case pf._kind == _qfKindInCIDR:
+			mismatch = fmt.Sprintf("!_filterInPrefixes(x, *%s)", filter)
+		case pf._kind == _qfKindText:
+			mismatch = fmt.Sprintf("!_filterHasTerms(%s, *%s)", fieldText(field, "x"), filter)
+		case pf._kind == _qfKindMultiValue:
+			mismatch = fmt.Sprintf("!slices.ContainsFunc(*%s, func(e %s) bool { return %s })",
+				filter, field._goType, generateEqualExpr(field, "x", "e"))
+		default:
+			mismatch = "!(" + generateEqualExpr(field, "x", "*"+filter) + ")"
+		}
+		checks = append(checks, fmt.Sprintf("if %s != nil && %s {\n\treturn false\n}", filter, mismatch))
+	}
+	return namedReplace(blockTmpl, map[string]string{
+		"$anySet":    strings.Join(anySet, " || "),
+		"$valueFunc": fieldValueFuncName(field),
+		"$checks":    strings.Join(checks, "\n"),
+	})
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateFieldValueFunc(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got := generateFieldValueFunc("Order", _field{
+		_originalName: "CustomerCountry",
+		_goType:       "string",
+		_path:         []pathStep{{_name: "Customer", _ptr: true}, {_name: "Country"}},
+	})
+
+	// Assert
+	require.Equal(t, `
+func _CustomerCountryValue(v *Order) (string, bool) {
+	if v.Customer == nil {
+	return *new(string), false
+}
+	return v.Customer.Country, true
+}
+`, got)
+}
+
+func Test_generateEqualExpr(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "x == e", generateEqualExpr(_field{_goType: "int"}, "x", "e"))
+	require.Equal(t, "x.Equal(*f.a)", generateEqualExpr(_field{_goType: "time.Time"}, "x", "*f.a"))
+	require.Equal(t, "x.Compare(e) == 0", generateEqualExpr(_field{_goType: "Version", _type: &fieldType{_comparer: true}}, "x", "e"))
+}
+
+func Test_generateMatchFunc(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "Age", _goType: "int", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}}}
+	pfs := []parserField{{_name: "_AgeLte", _kind: _qfKindRange, isRangeLte: true}, {_name: "_AgeGte", _kind: _qfKindRange, isRangeGte: true}}
+
+	// Act
+	got := generateMatchFunc("f", "_UserFilter", "User", []_field{field}, map[string][]parserField{"Age": pfs})
+
+	// Assert
+	require.Contains(t, got, `if f._AgeLte != nil || f._AgeGte != nil {
+	x, ok := _AgeValue(v)`)
+	require.Contains(t, got, "if f._AgeLte != nil && *f._AgeLte < x {")
+	require.Contains(t, got, "if f._AgeGte != nil && x < *f._AgeGte {")
+	require.Contains(t, got, "func (f *_UserFilter) Apply(items []User) []User {")
+}
@@ -0,0 +1,79 @@
+package parser
+
+func middlewareName(origStructName string) string {
+	return origStructName + "FilterMiddleware"
+}
+
+func filterFromContextName(origStructName string) string {
+	return origStructName + "FilterFromContext"
+}
+
+// generateMiddleware generates net/http middleware parsing the filters of every request into its context.
+func generateMiddleware(structName, origStructName string) string {
+	const tmpl = `
+type _filterContextKey struct{}
+
+// $middleware parses the filters of every request once with ParseFiltersRequest and stores them in the
+// request context, see $fromContext. Requests with invalid filters do not reach next, they are
+// answered by the responder set with WithFilterErrorResponder, RespondFilterError by default.
+func $middleware(next http.Handler, opts ...FilterOption) http.Handler {
+	respond := newFilterOptions(opts).respond
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := ParseFiltersRequest(r, opts...)
+		if err != nil {
+			respond(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), _filterContextKey{}, f)))
+	})
+}
+
+// $fromContext returns the filters stored by $middleware, false when the request did not pass it.
+func $fromContext(ctx context.Context) (*$structName, bool) {
+	f, ok := ctx.Value(_filterContextKey{}).(*$structName)
+	return f, ok
+}
+`
+	return namedReplace(tmpl, map[string]string{
+		"$middleware":  middlewareName(origStructName),
+		"$fromContext": filterFromContextName(origStructName),
+		"$structName":  structName,
+	})
+}
+
+const filterResponderDef = `
+// FilterPrecedence decides which values ParseFiltersRequest reads for keys sent both in the query
+// and in the form body.
+type FilterPrecedence int
+
+const (
+	// FilterPrecedenceForm reads form body values, like http.Request.FormValue. It is the default.
+	FilterPrecedenceForm FilterPrecedence = iota
+	// FilterPrecedenceQuery reads query values.
+	FilterPrecedenceQuery
+)
+
+// WithFilterPrecedence sets which values ParseFiltersRequest reads for keys sent both in the query
+// and in the form body, FilterPrecedenceForm by default.
+func WithFilterPrecedence(p FilterPrecedence) FilterOption {
+	return func(o *filterOptions) {
+		o.precedence = p
+	}
+}
+
+// FilterErrorResponder writes the response to a request with invalid filters, err is usually FilterErrors.
+type FilterErrorResponder func(w http.ResponseWriter, r *http.Request, err error)
+
+// WithFilterErrorResponder sets how the filter middleware answers requests with invalid filters.
+func WithFilterErrorResponder(respond FilterErrorResponder) FilterOption {
+	return func(o *filterOptions) {
+		o.respond = respond
+	}
+}
+
+// RespondFilterError answers with 400 Bad Request and the error as plain text, see FilterProblemResponder
+// for application/problem+json.
+func RespondFilterError(w http.ResponseWriter, _ *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+`
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got := generateMiddleware("_ProductFilter", "Product")
+
+	// Assert
+	require.Contains(t, got, "func ProductFilterMiddleware(next http.Handler, opts ...FilterOption) http.Handler {")
+	require.Contains(t, got, "func ProductFilterFromContext(ctx context.Context) (*_ProductFilter, bool) {")
+	require.Contains(t, got, "ctx.Value(_filterContextKey{}).(*_ProductFilter)")
+}
+
+func Test_generateParserFunc_entryPoints(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "Age",
+		_goType:       "uint",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "age", _defaultTo: ptr("18")},
+	}}
+	_, fieldMap := generateFilterStructDef("_ProductFilter", fields)
+	_, constMap := generateConstKeys(fields, fieldMap)
+
+	// Act
+	got, err := generateParserFunc("_ProductFilter", fields, nil, fieldMap, constMap)
+
+	// Assert
+	require.NoError(t, err)
+	require.Contains(t, got, "return ParseFiltersValues(inpAsUri.Query(), opts...)")
+	require.Contains(t, got, "func ParseFiltersQuery(rawQuery string, opts ...FilterOption) (*_ProductFilter, error) {")
+	require.Contains(t, got, "func ParseFiltersRequest(r *http.Request, opts ...FilterOption) (*_ProductFilter, error) {")
+	require.Contains(t, got, "func ParseFiltersValues(q url.Values, opts ...FilterOption) (*_ProductFilter, error) {")
+	// requests build their options once
+	require.Equal(t, 2, strings.Count(got, "newFilterOptions(opts)"))
+	require.Contains(t, got, "if o.precedence == FilterPrecedenceQuery {")
+	require.Contains(t, got, "return parseFiltersValues(q, o)")
+	// defaults are set on a copy of the values
+	require.Contains(t, got, "q = maps.Clone(q)")
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tStatus string `ufi:\"kind=exact;key=status\"`\n" +
+		"\tAge uint `ufi:\"kind=range;key=age;max=99\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func main() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := ProductFilterFromContext(r.Context())
+		if !ok {
+			fmt.Fprintln(w, "no filters")
+			return
+		}
+		fmt.Fprintln(w, f.GetStatusExact(), f.GetAgeGte())
+	})
+	serve := func(r *http.Request, opts ...FilterOption) {
+		w := httptest.NewRecorder()
+		ProductFilterMiddleware(handler, opts...).ServeHTTP(w, r)
+		fmt.Print(w.Code, " ", w.Body.String())
+	}
+	form := func(target, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	serve(httptest.NewRequest(http.MethodGet, "/p?status=new&age-from=18", nil))
+	serve(form("/p?status=used", "status=new&age-from=21"))
+	serve(form("/p?status=used", "status=new&age-from=21"), WithFilterPrecedence(FilterPrecedenceQuery))
+	serve(form("/p", "age-from=120"))
+	serve(form("/p", "age-from=120"), WithFilterErrorResponder(func(w http.ResponseWriter, _ *http.Request, err error) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintln(w, "custom:", err)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p?status=new", nil))
+	fmt.Print(w.Body.String())
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `200 new 18
+200 new 21
+200 used 21
+400 filter "age-from" (field Age): value "120" violates max=99
+422 custom: filter "age-from" (field Age): value "120" violates max=99
+no filters
+`, got)
+}
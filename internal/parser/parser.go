@@ -7,6 +7,9 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -28,12 +31,57 @@ func Run() error {
 	}
 	defer file.Close()
 
-	structSourceRows := []string{}
-	consumeStruct := false
+	var sourceRows []string
 	sc := bufio.NewScanner(file)
 	for sc.Scan() {
-		line := sc.Text()
-		if strings.TrimSpace(line) == "}" {
+		sourceRows = append(sourceRows, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("could not read file: %w", err)
+	}
+
+	goLine, _ := strconv.Atoi(os.Getenv("GOLINE"))
+	rules, err := parseStructRules(sourceRows, goLine)
+	if err != nil {
+		return fmt.Errorf("could not parse directives: %w", err)
+	}
+
+	// Fields are read from type checked sources when possible, this is the only way to learn about
+	// named, nested and embedded types. The struct source rows are the fallback.
+	fields, err := loadStructFields(filepath.Dir(sourceFile), outputFile, structName)
+	if err != nil {
+		log.Printf("could not load struct fields, nested structs and named types are not supported: %v", err)
+		fields = scanStructFields(sourceRows, structName)
+	}
+
+	code, err := GenerateCode(pkg, structName, fields, rules...)
+	if err != nil {
+		return fmt.Errorf("could not generate code: %w", err)
+	}
+
+	out, err := os.OpenFile(outputFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+
+	defer out.Close()
+	if _, err := out.WriteString(code); err != nil {
+		return fmt.Errorf("could not write to file: %w", err)
+	}
+
+	if err := exec.Command("goimports", "-w", outputFile).Run(); err != nil {
+		return fmt.Errorf("cannot run 'goimports' command: %w", err)
+	}
+
+	return nil
+}
+
+// scanStructFields reads the fields of the struct from its source rows, one field per row.
+func scanStructFields(sourceRows []string, structName string) []_field {
+	structSourceRows := []string{}
+	consumeStruct := false
+	for _, line := range sourceRows {
+		if consumeStruct && strings.TrimSpace(line) == "}" {
 			break
 		}
 
@@ -57,29 +105,18 @@ func Run() error {
 		f := consumeField(line)
 		parsedTag := parseFilterTag(f._tag)
 		f._qf = parsedTag
+		if elem, ok := strings.CutPrefix(f._goType, "[]"); ok && len(f._qf._kindList) > 0 {
+			f._goType, f._slice = elem, true
+		}
+		if elem, ok := strings.CutPrefix(f._goType, "map[string]"); ok && len(f._qf._kindList) > 0 {
+			f._goType, f._map = elem, true
+		}
+		if f._qf._lon != "" {
+			f._lon = &lonField{_name: f._qf._lon, _column: snakeCase(f._qf._lon)}
+		}
 		fields = append(fields, f)
 	}
-
-	code, err := GenerateCode(pkg, structName, fields)
-	if err != nil {
-		return fmt.Errorf("could not generate code: %w", err)
-	}
-
-	out, err := os.OpenFile(outputFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		return fmt.Errorf("could not open file: %w", err)
-	}
-
-	defer out.Close()
-	if _, err := out.WriteString(code); err != nil {
-		return fmt.Errorf("could not write to file: %w", err)
-	}
-
-	if err := exec.Command("goimports", "-w", outputFile).Run(); err != nil {
-		return fmt.Errorf("cannot run 'goimports' command: %w", err)
-	}
-
-	return nil
+	return fields
 }
 
 type _field struct {
@@ -87,6 +124,15 @@ type _field struct {
 	_goType       string
 	_tag          string
 	_qf           utiQueryFilter
+	_type         *fieldType
+	// _path leads from the filtered struct to the field, it is empty for fields read from source rows.
+	_path []pathStep
+	// _slice is set for slice fields, _goType is their element type then.
+	_slice bool
+	// _map is set for map fields with string keys, _goType is their value type then.
+	_map bool
+	// _lon is the longitude field paired with a geo filtered latitude field.
+	_lon *lonField
 }
 
 type _readFieldState int
@@ -135,33 +181,107 @@ const (
 	_qfKindRange      = queryFilterKind("range")
 	_qfKindMultiValue = queryFilterKind("multi-value")
 	_qfKindExact      = queryFilterKind("exact")
+	// _qfKindAny, _qfKindAll and _qfKindNone filter slice fields by the values they contain.
+	_qfKindAny  = queryFilterKind("any")
+	_qfKindAll  = queryFilterKind("all")
+	_qfKindNone = queryFilterKind("none")
+	// _qfKindMap filters map fields by the values of their keys, e.g. attr[color]=red.
+	_qfKindMap = queryFilterKind("map")
+	// _qfKindText filters string fields by the terms of a full-text query.
+	_qfKindText = queryFilterKind("text")
+	// _qfKindGeo filters latitude fields, paired with a longitude field, by radius and bounding box.
+	_qfKindGeo = queryFilterKind("geo")
+	// _qfKindInCIDR filters netip.Addr fields by the prefixes containing them, its key gets an -in suffix.
+	_qfKindInCIDR = queryFilterKind("in-cidr")
 )
 
-var _qfKindMap = map[queryFilterKind]struct{}{
+var _qfKinds = map[queryFilterKind]struct{}{
 	_qfKindRange:      {},
 	_qfKindMultiValue: {},
 	_qfKindExact:      {},
+	_qfKindAny:        {},
+	_qfKindAll:        {},
+	_qfKindNone:       {},
+	_qfKindMap:        {},
+	_qfKindText:       {},
+	_qfKindGeo:        {},
+	_qfKindInCIDR:     {},
+}
+
+func isSetKind(kind queryFilterKind) bool {
+	return kind == _qfKindAny || kind == _qfKindAll || kind == _qfKindNone
+}
+
+// isListKind reports whether values of kind are comma separated lists.
+func isListKind(kind queryFilterKind) bool {
+	return kind == _qfKindMultiValue || isSetKind(kind)
+}
+
+// setKindPostfix returns the postfix of the generated names of a set kind: Any, All or None.
+func setKindPostfix(kind queryFilterKind) string {
+	return strings.ToUpper(string(kind[:1])) + string(kind[1:])
 }
 
 func isValidQfKind(kind string) bool {
-	_, ok := _qfKindMap[queryFilterKind(kind)]
+	_, ok := _qfKinds[queryFilterKind(kind)]
 	return ok
 }
 
 const (
-	_tagNameKind = "kind"
-	_tagNameKey  = "key"
+	_tagName            = "ufi"
+	_tagNameKind        = "kind"
+	_tagNameKey         = "key"
+	_tagNameDefault     = "default"
+	_tagNameDefaultFrom = "default-from"
+	_tagNameDefaultTo   = "default-to"
+	_tagNameRequired    = "required"
+	_tagNameLayout      = "layout"
+	_tagNameTimezone    = "tz"
+	_tagNameUnit        = "unit"
+	_tagNameParser      = "parser"
+	_tagNameColumn      = "column"
+	_tagNameAttrs       = "attrs"
+	_tagNameLon         = "lon"
+	_tagNameScale       = "scale"
+	_tagNameDecimal     = "decimal"
+	_tagNameLocale      = "locale"
+	_tagNameBuckets     = "buckets"
+	_tagNameSelect      = "select"
 )
 
 type utiQueryFilter struct {
-	_kindList []queryFilterKind
-	_key      string
+	_kindList    []queryFilterKind
+	_key         string
+	_constraints []valueConstraint
+	_default     *string
+	_defaultFrom *string
+	_defaultTo   *string
+	_required    bool
+	_layout      string
+	_timezone    string
+	_unit        string
+	_parser      string
+	_column      string
+	_attrs       []mapAttr
+	_lon         string
+	// _scale is the number of decimal places of integer minor units, e.g. 2 for cents.
+	_scale string
+	// _decimal is set for string fields holding decimal numbers, compared exactly.
+	_decimal bool
+	// _locale names the locale numbers of the field are written in, see filterLocaleVars.
+	_locale string
+	// _buckets holds the edges of the facet histogram of a range field, or its time unit.
+	_buckets string
+	// _select allows selecting the field with the fields parameter, by _selectName or its key.
+	_select     bool
+	_selectName string
 }
 
 func parseFilterTag(s string) utiQueryFilter {
-	s = strings.Trim(s, "`")
-	s = strings.Trim(s, "ufi:")
-	s = strings.Trim(s, `"`)
+	if start, end := strings.Index(s, "`"), strings.LastIndex(s, "`"); start != -1 && end > start {
+		s = s[start+1 : end]
+	}
+	s = reflect.StructTag(s).Get(_tagName)
 	splitted := strings.Split(s, ";")
 	res := utiQueryFilter{}
 	for _, pair := range splitted {
@@ -169,13 +289,77 @@ func parseFilterTag(s string) utiQueryFilter {
 		if pair == "" {
 			continue
 		}
-		splittedPair := strings.Split(pair, "=")
-		if len(splittedPair) != 2 {
-			log.Printf("ignoring qf-pair: [%v;%d]", splittedPair, len(splittedPair))
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			if pair == _tagNameRequired {
+				res._required = true
+				continue
+			}
+			if pair == _tagNameDecimal {
+				res._decimal = true
+				continue
+			}
+			if pair == _tagNameSelect {
+				res._select = true
+				continue
+			}
+			log.Printf("ignoring qf-pair: [%s]", pair)
+			continue
+		}
+
+		switch key {
+		case _tagNameDefault:
+			res._default = &value
+			continue
+		case _tagNameDefaultFrom:
+			res._defaultFrom = &value
+			continue
+		case _tagNameDefaultTo:
+			res._defaultTo = &value
+			continue
+		case _tagNameLayout:
+			res._layout = value
+			continue
+		case _tagNameTimezone:
+			res._timezone = value
+			continue
+		case _tagNameUnit:
+			res._unit = value
+			continue
+		case _tagNameParser:
+			res._parser = value
+			continue
+		case _tagNameColumn:
+			res._column = value
+			continue
+		case _tagNameAttrs:
+			res._attrs = parseMapAttrs(value)
+			continue
+		case _tagNameLon:
+			res._lon = value
+			continue
+		case _tagNameScale:
+			res._scale = value
+			continue
+		case _tagNameLocale:
+			res._locale = value
+			continue
+		case _tagNameBuckets:
+			res._buckets = value
+			continue
+		case _tagNameSelect:
+			res._select, res._selectName = true, value
+			continue
+		}
+
+		if isValidConstraintKind(key) {
+			res._constraints = append(res._constraints, valueConstraint{
+				_kind:  constraintKind(key),
+				_value: value,
+			})
 			continue
 		}
 
-		key, value := splittedPair[0], splittedPair[1]
 		if key == _tagNameKey {
 			res._key = value
 		}
@@ -198,20 +382,41 @@ func parseFilterTag(s string) utiQueryFilter {
 	return res
 }
 
-func generateQueryValueParser(variable string, fieldName, qfKeyConstName, staticParserFuncName, fieldType string) string {
+func generateQueryValueParser(variable string, field _field, pf parserField, qfKeyConstName string) string {
 	const tmpl = `
-if q.Has($key) {
+if q.Has($key)$exactGuard {
 	$keyRaw:=q.Get($key)
-	$keyParsed:=$gotypeParser[$fieldType]($keyRaw)
-	$var.$fieldName=&$keyParsed
+	$keyParsed, err:=$parseCall
+	if err != nil {
+		errs.add(&FilterError{Field: "$errField", Key: $key, Constraint: FilterConstraintType, Value: $keyRaw, Err: err})
+	} else {
+		$validate
+		$var.$fieldName=&$keyParsed
+	}
 }
 `
+	parseCall := generateParseCall(field, pf, qfKeyConstName)
+	var validate string
+	if len(fieldConstraints(field)) > 0 {
+		validate = generateValidatorCall(field, pf, qfKeyConstName, qfKeyConstName+"Parsed")
+	}
+	// A comma separated value of a key shared by exact and multi-value kinds is a multi-value filter.
+	var exactGuard string
+	if pf._kind == _qfKindExact && hasKind(field, _qfKindMultiValue) {
+		sep := `","`
+		if isLocalized(field) {
+			sep = fmt.Sprintf("_filterListSep(%s)", generateLocaleExpr(field, pf, qfKeyConstName))
+		}
+		exactGuard = fmt.Sprintf(` && !strings.Contains(q.Get(%s), %s)`, qfKeyConstName, sep)
+	}
 	return namedReplace(tmpl, map[string]string{
-		"$var":          variable,
-		"$fieldName":    fieldName,
-		"$key":          qfKeyConstName,
-		"$gotypeParser": staticParserFuncName,
-		"$fieldType":    strings.TrimLeft(fieldType, "[]"),
+		"$exactGuard": exactGuard,
+		"$parseCall":  parseCall,
+		"$validate":   validate,
+		"$var":        variable,
+		"$fieldName":  pf._name,
+		"$errField":   fieldDisplayName(field),
+		"$key":        qfKeyConstName,
 	})
 }
 
@@ -239,6 +444,8 @@ type parserField struct {
 	_kind      queryFilterKind
 	isRangeLte bool
 	isRangeGte bool
+	// _geo is the part of a geo filter the field holds: near, radius or bbox.
+	_geo geoPart
 }
 
 func generateFilterStructDef(structName string, fields []_field) (string, map[string][]parserField) {
@@ -269,6 +476,55 @@ func generateFilterStructDef(structName string, fields []_field) (string, map[st
 					"$fieldName": parserName,
 					"$goType":    "*[]" + field._goType,
 				}))
+			case _qfKindAny, _qfKindAll, _qfKindNone:
+				parserName := "_" + field._originalName + setKindPostfix(kind)
+				fieldMap[field._originalName] = append(fieldMap[field._originalName], parserField{
+					_name: parserName,
+					_kind: kind,
+				})
+				rows = append(rows, namedReplace(tmpl, map[string]string{
+					"$fieldName": parserName,
+					"$goType":    "*[]" + field._goType,
+				}))
+			case _qfKindMap:
+				parserName := "_" + field._originalName + "Map"
+				fieldMap[field._originalName] = append(fieldMap[field._originalName], parserField{
+					_name: parserName,
+					_kind: kind,
+				})
+				rows = append(rows, namedReplace(tmpl, map[string]string{
+					"$fieldName": parserName,
+					"$goType":    "*map[string]" + field._goType,
+				}))
+			case _qfKindText:
+				parserName := "_" + field._originalName + "Text"
+				fieldMap[field._originalName] = append(fieldMap[field._originalName], parserField{
+					_name: parserName,
+					_kind: kind,
+				})
+				rows = append(rows, namedReplace(tmpl, map[string]string{
+					"$fieldName": parserName,
+					"$goType":    "*[]string",
+				}))
+			case _qfKindInCIDR:
+				parserName := "_" + field._originalName + "InCIDR"
+				fieldMap[field._originalName] = append(fieldMap[field._originalName], parserField{
+					_name: parserName,
+					_kind: kind,
+				})
+				rows = append(rows, namedReplace(tmpl, map[string]string{
+					"$fieldName": parserName,
+					"$goType":    "*[]netip.Prefix",
+				}))
+			case _qfKindGeo:
+				for _, part := range _geoParts {
+					pf := parserField{_name: "_" + field._originalName + geoPartPostfix(part), _kind: kind, _geo: part}
+					fieldMap[field._originalName] = append(fieldMap[field._originalName], pf)
+					rows = append(rows, namedReplace(tmpl, map[string]string{
+						"$fieldName": pf._name,
+						"$goType":    "*" + parserFieldType(field, pf),
+					}))
+				}
 			case _qfKindRange:
 				parserNameLte := "_" + field._originalName + "Lte"
 				fieldMap[field._originalName] = append(fieldMap[field._originalName], parserField{
@@ -294,6 +550,14 @@ func generateFilterStructDef(structName string, fields []_field) (string, map[st
 			}
 		}
 	}
+	if hasDefaults(fields) {
+		rows = append(rows, "_defaulted map[string]bool")
+	}
+	// the query and options the filters were parsed with, see MarshalJSON
+	if len(selectableFields(fields)) > 0 {
+		rows = append(rows, "_fields []string")
+	}
+	rows = append(rows, "_query url.Values", "_options *filterOptions")
 	rows = append(rows, "}")
 	return strings.Join(rows, "\n"), fieldMap
 }
@@ -332,7 +596,7 @@ func generateConstKeys(fields []_field, structFieldMap map[string][]parserField)
 				parserFieldToConst[pf] = gteValues["$constName"]
 				rows = append(rows, namedReplace(constTmpl, gteValues))
 			}
-			if pf._kind == _qfKindMultiValue || pf._kind == _qfKindExact {
+			if pf._kind == _qfKindMultiValue || pf._kind == _qfKindExact || pf._kind == _qfKindMap || pf._kind == _qfKindText {
 				multiValueOrExactValues := map[string]string{
 					"$constName": fmt.Sprintf("_%sKey", field._originalName),
 					"$key":       fmt.Sprintf("%s", field._qf._key),
@@ -341,11 +605,26 @@ func generateConstKeys(fields []_field, structFieldMap map[string][]parserField)
 				parserFieldToConst[pf] = multiValueOrExactValues["$constName"]
 				rows = append(rows, namedReplace(constTmpl, multiValueOrExactValues))
 			}
+			if isSetKind(pf._kind) || pf._kind == _qfKindGeo || pf._kind == _qfKindInCIDR {
+				suffix := string(pf._kind)
+				switch {
+				case pf._geo != "":
+					suffix = string(pf._geo)
+				case pf._kind == _qfKindInCIDR:
+					suffix = "in"
+				}
+				setValues := map[string]string{
+					"$constName": fmt.Sprintf("_%sKey_%s", field._originalName, suffix),
+					"$key":       queryKey(field, pf),
+				}
+				parserFieldToConst[pf] = setValues["$constName"]
+				rows = append(rows, namedReplace(constTmpl, setValues))
+			}
 		}
 	}
 
 	uniqueRows := make(map[string]struct{})
-	uRows := make([]string, len(rows))
+	uRows := make([]string, 0, len(rows))
 	for _, row := range rows {
 		if _, ok := uniqueRows[row]; ok {
 			continue
@@ -357,7 +636,7 @@ func generateConstKeys(fields []_field, structFieldMap map[string][]parserField)
 	return strings.Join(uRows, "\n"), parserFieldToConst
 }
 
-func generateParserFunc(structName string, fields []_field, structFieldsMap map[string][]parserField, qfConstKeyMap map[parserField]string) string {
+func generateParserFunc(structName string, fields []_field, rules []_structRule, structFieldsMap map[string][]parserField, qfConstKeyMap map[parserField]string) (string, error) {
 	var queryParserRows []string
 	for _, field := range fields {
 		parserFields, ok := structFieldsMap[field._originalName]
@@ -365,36 +644,123 @@ func generateParserFunc(structName string, fields []_field, structFieldsMap map[
 			continue
 		}
 		for _, pf := range parserFields {
-			goType := ternary(pf._kind == _qfKindMultiValue, "[]"+field._goType, field._goType)
+			if pf._kind == _qfKindMap {
+				queryParserRows = append(queryParserRows, generateMapParser("res", field, pf, qfConstKeyMap[pf]))
+				continue
+			}
+			if pf._kind == _qfKindText {
+				queryParserRows = append(queryParserRows, generateTextParser("res", field, pf, qfConstKeyMap[pf]))
+				continue
+			}
+			if pf._kind == _qfKindGeo {
+				queryParserRows = append(queryParserRows, generateGeoParser("res", field, pf, qfConstKeyMap))
+				continue
+			}
+			if pf._kind == _qfKindInCIDR {
+				queryParserRows = append(queryParserRows, generateCIDRParser("res", field, pf, qfConstKeyMap[pf]))
+				continue
+			}
 			queryParserRows = append(queryParserRows, generateQueryValueParser(
 				"res",
-				pf._name,
-				qfConstKeyMap[pf],
-				goTypeParserFuncs[goType],
-				goType))
+				field,
+				pf,
+				qfConstKeyMap[pf]))
 		}
 	}
+	presenceChecks, err := generatePresenceRuleChecks(rules, fields, structFieldsMap, qfConstKeyMap)
+	if err != nil {
+		return "", err
+	}
+	rangeChecks, err := generateRangeChecks(rules, fields, structFieldsMap, qfConstKeyMap)
+	if err != nil {
+		return "", err
+	}
 	const parseFuncTmpl = `
-func ParseFilters(input string) (*$structName, error) {
+// ParseFilters parses the filters of the query of the URL input, see ParseFiltersValues.
+func ParseFilters(input string, opts ...FilterOption) (*$structName, error) {
 	inpAsUri, err := url.Parse(input)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse url: %w", err)
 	}
-	q := inpAsUri.Query()
-	res := new($structName)
+	return ParseFiltersValues(inpAsUri.Query(), opts...)
+}
+
+// ParseFiltersQuery parses the filters of a raw query such as url.URL.RawQuery, see ParseFiltersValues.
+func ParseFiltersQuery(rawQuery string, opts ...FilterOption) (*$structName, error) {
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse query: %w", err)
+	}
+	return ParseFiltersValues(q, opts...)
+}
+
+// ParseFiltersRequest parses the filters of r from its query and its form body, read with r.ParseForm.
+// Keys sent in both are read from the form body unless WithFilterPrecedence says otherwise.
+func ParseFiltersRequest(r *http.Request, opts ...FilterOption) (*$structName, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("cannot parse form: %w", err)
+	}
+	o := newFilterOptions(opts)
+	first, second := r.PostForm, r.URL.Query()
+	if o.precedence == FilterPrecedenceQuery {
+		first, second = second, first
+	}
+	q := make(url.Values, len(r.Form))
+	for _, values := range []url.Values{second, first} {
+		for key, v := range values {
+			q[key] = v
+		}
+	}
+	return parseFiltersValues(q, o)
+}
+
+// ParseFiltersValues parses the filters of q, which is not modified. The errors of invalid values
+// are returned together as FilterErrors.
+func ParseFiltersValues(q url.Values, opts ...FilterOption) (*$structName, error) {
+	return parseFiltersValues(q, newFilterOptions(opts))
+}
+
+// parseFiltersValues parses the filters of q with o. The options are built once per parse, so
+// relative times are resolved against a single now.
+func parseFiltersValues(q url.Values, o *filterOptions) (*$structName, error) {
+	res := &$structName{_query: _filterSavedQuery(q), _options: o}
+	var errs FilterErrors
+	$requiredChecks
+	$presenceChecks
+	$applyDefaults
 	$queryParsers
+	$fieldsParser
+	$rangeChecks
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return res, nil
 }`
-	return namedReplace(parseFuncTmpl, map[string]string{
-		"$structName":   structName,
-		"$queryParsers": strings.Join(queryParserRows, "\n"),
+	var applyDefaults string
+	if hasDefaults(fields) {
+		applyDefaults = applyDefaultsCode
+	}
+	parseFunc := namedReplace(parseFuncTmpl, map[string]string{
+		"$requiredChecks": generateRequiredChecks(fields, structFieldsMap, qfConstKeyMap),
+		"$presenceChecks": presenceChecks,
+		"$applyDefaults":  applyDefaults,
+		"$structName":     structName,
+		"$queryParsers":   strings.Join(queryParserRows, "\n"),
+		"$fieldsParser":   generateFieldsParser(fields),
+		"$rangeChecks":    rangeChecks,
 	})
+	if presenceChecks != "" {
+		parseFunc += filterKeysPresentFunc
+	}
+	return parseFunc, nil
 }
 
-func GenerateCode(pkg, structName string, fields []_field) (string, error) {
+func GenerateCode(pkg, structName string, fields []_field, rules ..._structRule) (string, error) {
+	origStructName := structName
 	structName = fmt.Sprintf("_%sFilter", structName)
 	structRcv := structrcv(structName)
 	structDef, structFieldMap := generateFilterStructDef(structName, fields)
+	constantsDef, parserFieldToConstMap := generateConstKeys(fields, structFieldMap)
 	// Generate getters
 	var getters []string
 	for originalField, parserFields := range structFieldMap {
@@ -417,49 +783,224 @@ func GenerateCode(pkg, structName string, fields []_field) (string, error) {
 				if pf._kind == _qfKindMultiValue {
 					postfix = "Array"
 				}
+				if isSetKind(pf._kind) {
+					postfix = setKindPostfix(pf._kind)
+				}
+				if pf._kind == _qfKindMap {
+					postfix = "Map"
+				}
+				if pf._kind == _qfKindText {
+					postfix = "Text"
+				}
+				if pf._kind == _qfKindGeo {
+					postfix = geoPartPostfix(pf._geo)
+				}
+				if pf._kind == _qfKindInCIDR {
+					postfix = "InCIDR"
+				}
 
-				getters = append(getters, generateFieldGetterFunc(
+				getter := generateFieldGetterFunc(
 					structRcv,
 					structName,
 					originalField,
 					pf._name,
 					postfix,
-					ternary(pf._kind == _qfKindMultiValue, "[]"+field._goType, field._goType),
+					parserFieldType(field, pf),
+				)
+				if doc := generateEnumGetterDoc(field, pf, postfix); doc != "" {
+					getter = doc + strings.TrimPrefix(getter, "\n")
+				}
+				getters = append(getters, getter)
+				getters = append(getters, generateFieldPresenceFuncs(
+					structRcv,
+					structName,
+					field,
+					pf,
+					postfix,
+					parserFieldToConstMap[pf],
+					hasDefaults(fields),
 				))
 			}
 
 		}
 	}
 
-	uniqueTypes := make(map[string]struct{})
+	uniqueParsers := make(map[string]struct{})
+	var parsers []string
+	var validators []string
 	for _, field := range fields {
+		if len(field._qf._kindList) == 0 {
+			continue
+		}
+		addParser := func(name, code string) {
+			if _, ok := uniqueParsers[name]; !ok {
+				uniqueParsers[name] = struct{}{}
+				parsers = append(parsers, code)
+			}
+		}
+		vp, err := resolveValueParser(field)
+		if err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		switch vp {
+		case _valueParserCustom:
+			addParser("_filterCustomParse", customParserRegistryDef)
+		case _valueParserText:
+			addParser("vtextparse", textParseFunc)
+		default:
+			basic := fieldBasicType(field)
+			addParser(goTypeParserFuncs[basic], generateQueryValueParserForGoType(basic))
+		}
+		if err := validateSetKinds(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
 		for _, kind := range field._qf._kindList {
-			field._goType = strings.Trim(field._goType, "64")
-			field._goType = strings.Trim(field._goType, "32")
-			uniqueTypes[field._goType] = struct{}{}
-			if kind == _qfKindMultiValue {
-				uniqueTypes["[]"+field._goType] = struct{}{}
+			if isListKind(kind) {
+				addParser("gsliceparse", genericSliceParseFunc)
+			}
+		}
+		if field._slice {
+			addParser("_filterHasAny", filterSetMatchFuncs)
+		}
+		if err := validateMapKind(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if field._map {
+			addParser("_filterParseAttrs", filterMapFuncs)
+			validators = append(validators, generateMapAttrsVar(field))
+		}
+		if err := validateTextKind(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if hasKind(field, _qfKindText) {
+			addParser("_filterTerms", filterTextFuncs)
+		}
+		if err := validateGeoKind(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if hasKind(field, _qfKindGeo) {
+			addParser("_filterGeoDistance", filterGeoFuncs)
+		}
+		if err := validateCIDRKind(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if hasKind(field, _qfKindInCIDR) {
+			addParser("gsliceparse", genericSliceParseFunc)
+			addParser("_filterParsePrefix", filterCIDRFuncs)
+		}
+		if err := validateDecimal(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if field._qf._scale != "" {
+			addParser("gscaleparse", genericScaleParseFunc)
+		}
+		if field._qf._decimal {
+			addParser("_filterParseDecimal", filterDecimalFuncs)
+		}
+		if err := validateLocale(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if isLocalized(field) {
+			addParser("glocaleparse", filterLocaleFuncs)
+		}
+		if err := validateBuckets(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if hasKind(field, _qfKindRange) {
+			if _, ok := generateLessExpr(field, "a", "b"); !ok && vp != _valueParserBuiltin {
+				return "", fmt.Errorf("field %s: range kind requires a Compare(%s) int method", field._originalName, field._goType)
 			}
 		}
-	}
 
-	var parsers []string
-	for t := range uniqueTypes {
-		p := generateQueryValueParserForGoType(t)
-		parsers = append(parsers, p)
+		if err := validateTimeOptions(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if err := validateUnit(field); err != nil {
+			return "", fmt.Errorf("field %s: %w", field._originalName, err)
+		}
+		if field._qf._unit != "" {
+			family, _, _ := resolveUnit(field._qf._unit)
+			addParser(family._varName, generateUnitTable(family))
+			addParser("gunitparse", genericUnitParseFunc)
+		}
+		if locationVar := generateTimeLocationVar(field); locationVar != "" {
+			validators = append(validators, locationVar)
+		}
+
+		if len(fieldConstraints(field)) > 0 {
+			validator, err := generateValidatorFunc(field)
+			if err != nil {
+				return "", fmt.Errorf("field %s: %w", field._originalName, err)
+			}
+			validators = append(validators, validator)
+		}
 	}
 
-	constantsDef, parserFieldToConstMap := generateConstKeys(fields, structFieldMap)
-	parserFunc := generateParserFunc(structName, fields, structFieldMap, parserFieldToConstMap)
+	if err := validateSelect(fields); err != nil {
+		return "", err
+	}
+	var extraQueryKeys []string
+	if len(selectableFields(fields)) > 0 {
+		extraQueryKeys = append(extraQueryKeys, "_filterFieldsKey")
+	}
+	defaultsDef, err := generateDefaultsDef(fields, structFieldMap, parserFieldToConstMap)
+	if err != nil {
+		return "", err
+	}
+	parserFunc, err := generateParserFunc(structName, fields, rules, structFieldMap, parserFieldToConstMap)
+	if err != nil {
+		return "", err
+	}
 	rows := []string{
 		"// CODE IS GENERATED AUTOMATICALLY, DO NOT EDIT!!!",
 		fmt.Sprintf(`package %s`, pkg),
 		constantsDef,
+		generateFilterEnumsDef(fields, structFieldMap, parserFieldToConstMap),
+		defaultsDef,
 		structDef,
+		filterOptionsDef,
+		filterLocaleDef,
+		filterErrorDef,
 		parserFunc,
+		generateJSONKeysDef(fields, structFieldMap, parserFieldToConstMap),
+		generateJSONParseFunc(structName),
+		filterJSONFuncs,
+		generateQueryKeysDef(parserFieldToConstMap, extraQueryKeys...),
+		generateSavedFilterFuncs(structRcv, structName, rules),
+		filterSavedFuncs,
+		filterResponderDef,
+		filterProblemDef,
+		generateMiddleware(structName, origStructName),
 	}
 
+	if hasTimezones(fields) {
+		rows = append(rows, filterLoadLocationFunc)
+	}
+	rows = append(rows, validators...)
 	rows = append(rows, getters...)
+	rows = append(rows, generateMatchFunc(structRcv, structName, origStructName, fields, structFieldMap))
+	for _, field := range fields {
+		if hasKind(field, _qfKindGeo) {
+			rows = append(rows, generateGeoSortFunc(structRcv, structName, origStructName, field))
+		}
+	}
+	for _, field := range fields {
+		if len(structFieldMap[field._originalName]) > 0 || field._qf._select {
+			rows = append(rows, generateFieldValueFunc(origStructName, field))
+		}
+	}
+	rows = append(rows, generateWhereFunc(structRcv, structName, fields, structFieldMap), filterWhereDef)
+	rows = append(rows, generateSubscriptionIndex(structName, origStructName, fields, structFieldMap), filterSubscriptionFuncs)
+	rows = append(rows, generateQueryIndex(structName, origStructName, fields, structFieldMap), filterQueryIndexFuncs)
+	if projection := generateProjection(structRcv, structName, origStructName, fields); projection != "" {
+		rows = append(rows, projection, filterProjectionFuncs)
+	}
+	if facets := generateFacets(structRcv, structName, origStructName, fields, structFieldMap); facets != "" {
+		rows = append(rows, facets, filterFacetFuncs)
+	}
+	if index := generateTextIndex(structName, origStructName, fields, structFieldMap); index != "" {
+		rows = append(rows, index)
+	}
 	rows = append(rows, parsers...)
 
 	result := strings.Builder{}
@@ -480,79 +1021,91 @@ func structrcv(structName string) string {
 	return structName[:3]
 }
 
+func hasKind(field _field, kind queryFilterKind) bool {
+	for _, k := range field._qf._kindList {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// generateParseCall generates an expression parsing the raw value of the qfKeyConstName key into the value of pf.
+func generateParseCall(field _field, pf parserField, qfKeyConstName string) string {
+	rawVar := qfKeyConstName + "Raw"
+	vp, _ := resolveValueParser(field)
+	if vp == _valueParserBuiltin && field._goType == "time.Time" {
+		return generateTimeParseCall(field, pf, rawVar)
+	}
+	parseFunc := generateParseFunc(field, vp)
+	if isLocalized(field) {
+		return generateLocaleParseCall(field, pf, qfKeyConstName, rawVar, parseFunc)
+	}
+	if isListKind(pf._kind) {
+		return fmt.Sprintf("gsliceparse(%s, %s)", rawVar, parseFunc)
+	}
+	return fmt.Sprintf("%s(%s)", parseFunc, rawVar)
+}
+
 var goTypeParserFuncs = map[string]string{
-	"string":    "vstrparse",
-	"bool":      "vboolparse",
-	"int":       "gintparse",
-	"[]int":     "gsliceintparse",
-	"int32":     "gintparse",
-	"[]int32":   "gsliceintparse",
-	"int64":     "gintparse",
-	"[]int64":   "gsliceintparse",
-	"uint":      "guintparse",
-	"[]uint":    "gsliceuintparse",
-	"uint32":    "guintparse",
-	"[]uint32":  "gsliceuintparse",
-	"uint64":    "guintparse",
-	"[]uint64":  "gsliceuintparse",
-	"float":     "gfloatparse",
-	"float32":   "gfloatparse",
-	"float64":   "gfloatparse",
-	"time.Time": "vtimeparse",
+	"string":        "vstrparse",
+	"bool":          "vboolparse",
+	"int":           "gintparse",
+	"int32":         "gintparse",
+	"int64":         "gintparse",
+	"uint":          "guintparse",
+	"uint32":        "guintparse",
+	"uint64":        "guintparse",
+	"float32":       "gfloatparse",
+	"float64":       "gfloatparse",
+	"time.Time":     "vtimeparse",
+	"time.Duration": "vdurationparse",
 }
 
 const (
 	genericIntParseFunc = `
-func gintparse[I int|int32|int64](inp string) I {
+func gintparse[I int|int32|int64](inp string) (I, error) {
 	v, err := strconv.ParseInt(inp, 10, 64)
 	if err != nil {
-		return *new(I)
+		return *new(I), err
 	}
-	return I(v)
+	if int64(I(v)) != v {
+		return *new(I), strconv.ErrRange
+	}
+	return I(v), nil
 }`
 	genericUintParseFunc = `
-func guintparse[I uint|uint32|uint64](inp string) I {
+func guintparse[I uint|uint32|uint64](inp string) (I, error) {
 	v, err := strconv.ParseUint(inp, 10, 64)
 	if err != nil {
-		return *new(I)
+		return *new(I), err
+	}
+	if uint64(I(v)) != v {
+		return *new(I), strconv.ErrRange
 	}
-	return I(v)
+	return I(v), nil
 }`
 	genericFloatParseFunc = `
-func gfloatparse[I float32|float64](inp string) I {
+func gfloatparse[I float32|float64](inp string) (I, error) {
 	v, err := strconv.ParseFloat(inp, 64)
 	if err != nil {
-		return *new(I)
+		return *new(I), err
 	}
-	return I(v)
+	return I(v), nil
 }`
 
-	genericIntSliceParseFunc = `
-func gsliceintparse[I int|int32|int64](inp string) []I {
-	splitted := strings.Split(inp, ",")
-	result := make([]I, 0, len(splitted))
-	for _, v := range splitted {
-		result = append(result, gintparse[I](v))
-	}
-	return result
-}`
-	genericUintSliceParseFunc = `
-func gsliceuintparse[I uint|uint32|uint64](inp string) []I {
+	genericSliceParseFunc = `
+func gsliceparse[T any](inp string, parse func(string) (T, error)) ([]T, error) {
 	splitted := strings.Split(inp, ",")
-	result := make([]I, 0, len(splitted))
+	result := make([]T, 0, len(splitted))
 	for _, v := range splitted {
-		result = append(result, guintparse[I](v))
-	}
-	return result
-}`
-	genericFloatSliceParseFunc = `
-func gslicefloatparse[I float64|float32](inp string) []I {
-	splitted := strings.Split(inp, ",")
-	result := make([]I, 0, len(splitted))
-	for _, v := range splitted {
-		result = append(result, gfloatparse[I](v))
+		parsed, err := parse(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
 	}
-	return result
+	return result, nil
 }`
 )
 
@@ -561,35 +1114,23 @@ func generateQueryValueParserForGoType(goType string) string {
 	switch goType {
 	case "int", "int64", "int32":
 		return genericIntParseFunc
-	case "[]int", "[]int64", "[]int32":
-		return genericIntSliceParseFunc
 	case "uint", "uint64", "uint32":
 		return genericUintParseFunc
-	case "[]uint", "[]uint64", "[]uint32":
-		return genericUintSliceParseFunc
-	case "float", "float32", "float64":
+	case "float32", "float64":
 		return genericFloatParseFunc
-	case "[]float32", "[]float64":
-		return genericFloatSliceParseFunc
 	case "string":
-		funcTemplate = `func %s[T any](inp string) string {return inp}`
+		funcTemplate = `func %s[T any](inp string) (string, error) {return inp, nil}`
 	case "bool":
 		funcTemplate = `
-func %s[T any](inp string) bool {
-	v, err := strconv.ParseBool(inp)
-	if err != nil {
-		return false
-	}
-	return v
+func %s[T any](inp string) (bool, error) {
+	return strconv.ParseBool(inp)
 }`
 	case "time.Time":
+		return timeParseFunc
+	case "time.Duration":
 		funcTemplate = `
-func %s[T any](inp string) time.Time {
-	v, err := time.Parse(time.RFC3339, inp)
-	if err != nil {
-		return time.Time{}
-	}
-	return v
+func %s[T any](inp string) (time.Duration, error) {
+	return time.ParseDuration(inp)
 }`
 	}
 	return fmt.Sprintf(funcTemplate, goTypeParserFuncs[goType])
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	goparser "go/parser"
+	"go/token"
 	"strings"
 	"testing"
 
@@ -17,7 +19,7 @@ func Test_parseFilterTag(t *testing.T) {
 	}{
 		{
 			name:  "basic",
-			input: "`uti:\"qf-kind=range;qf-key=createdAt\"",
+			input: "`ufi:\"kind=range;key=createdAt\"`",
 			want: utiQueryFilter{
 				_kindList: []queryFilterKind{_qfKindRange},
 				_key:      "createdAt",
@@ -25,12 +27,110 @@ func Test_parseFilterTag(t *testing.T) {
 		},
 		{
 			name:  "multiple kinds",
-			input: "`uti:\"qf-kind=range,exact;qf-key=createdAt\"",
+			input: "`ufi:\"kind=range,exact;key=createdAt\"`",
 			want: utiQueryFilter{
 				_kindList: []queryFilterKind{_qfKindRange, _qfKindExact},
 				_key:      "createdAt",
 			},
 		},
+		{
+			name:  "constraints",
+			input: "`json:\"condition\" ufi:\"kind=exact;key=condition;min=1;enum=new,used;pattern=^[a-z=]+$\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindExact},
+				_key:      "condition",
+				_constraints: []valueConstraint{
+					{_kind: _constraintMin, _value: "1"},
+					{_kind: _constraintEnum, _value: "new,used"},
+					{_kind: _constraintPattern, _value: "^[a-z=]+$"},
+				},
+			},
+		},
+		{
+			name:  "defaults and required",
+			input: "`ufi:\"kind=exact,range;key=status;default=active;default-from=a;default-to=z;required\"`",
+			want: utiQueryFilter{
+				_kindList:    []queryFilterKind{_qfKindExact, _qfKindRange},
+				_key:         "status",
+				_default:     ptr("active"),
+				_defaultFrom: ptr("a"),
+				_defaultTo:   ptr("z"),
+				_required:    true,
+			},
+		},
+		{
+			name:  "time options",
+			input: "`ufi:\"kind=range;key=created;layout=2006-01-02 15:04;tz=Europe/Berlin\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindRange},
+				_key:      "created",
+				_layout:   "2006-01-02 15:04",
+				_timezone: "Europe/Berlin",
+			},
+		},
+		{
+			name:  "parser and unit",
+			input: "`ufi:\"kind=exact;key=color;parser=color;unit=B\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindExact},
+				_key:      "color",
+				_parser:   "color",
+				_unit:     "B",
+			},
+		},
+		{
+			name:  "map attrs",
+			input: "`ufi:\"kind=map;key=attr;attrs=color,size:int\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindMap},
+				_key:      "attr",
+				_attrs:    []mapAttr{{_name: "color"}, {_name: "size", _type: "int"}},
+			},
+		}, {
+			name:  "decimal options",
+			input: "`ufi:\"kind=range;key=amount;scale=2;decimal\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindRange},
+				_key:      "amount",
+				_scale:    "2",
+				_decimal:  true,
+			},
+		},
+		{
+			name:  "locale",
+			input: "`ufi:\"kind=range;key=price;locale=ru\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindRange},
+				_key:      "price",
+				_locale:   "ru",
+			},
+		},
+		{
+			name:  "buckets",
+			input: "`ufi:\"kind=range;key=price;buckets=0,50,100\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindRange},
+				_key:      "price",
+				_buckets:  "0,50,100",
+			},
+		},
+		{
+			name:  "select",
+			input: "`ufi:\"kind=exact;key=name;select\" json:\"name\"`",
+			want: utiQueryFilter{
+				_kindList: []queryFilterKind{_qfKindExact},
+				_key:      "name",
+				_select:   true,
+			},
+		},
+		{
+			name:  "select name",
+			input: "`ufi:\"select=title\"`",
+			want: utiQueryFilter{
+				_select:     true,
+				_selectName: "title",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -139,6 +239,8 @@ func Test_generateFilterStructDef(t *testing.T) {
 		`_priceLte *float64`,
 		`_priceGte *float64`,
 		`_priceMultiValue *[]float64`,
+		`_query url.Values`,
+		`_options *filterOptions`,
 		`}`,
 	}
 	require.Equal(t, strings.Join(want, "\n"), got)
@@ -208,18 +310,29 @@ func TestGenerateCode(t *testing.T) {
 
 	// Assert
 	require.NoError(t, err)
-	require.Equal(t, strings.TrimSpace(`
-// CODE IS GENERATED AUTOMATICALLY, DO NOT EDIT!!!
+	_, err = goparser.ParseFile(token.NewFileSet(), "ufi_product.go", got, 0)
+	require.NoError(t, err)
+	head, _, ok := strings.Cut(got, "// FilterOption configures")
+	require.True(t, ok)
+	require.Equal(t, `// CODE IS GENERATED AUTOMATICALLY, DO NOT EDIT!!!
 package my_package
 const _nameKey = "name"
+const _priceKey_lte = "price-to"
+const _priceKey_gte = "price-from"
 const _priceKey = "price"
-const _priceKeyGte = "price-from"
-const _priceKeyLte = "price-to"
-type _filterProduct struct {
-	_nameExact *string
-	_priceLte  *float64
-	_priceGte  *float64
-	_priceMultiValue []float64
+
+
+type _ProductFilter struct{
+_nameExact *string
+_priceLte *float64
+_priceGte *float64
+_priceMultiValue *[]float64
+_query url.Values
+_options *filterOptions
+}
+`, strings.TrimRight(head, "\n")+"\n")
 }
-`), got)
+
+func ptr[T any](v T) *T {
+	return &v
 }
@@ -0,0 +1,77 @@
+package parser
+
+const filterProblemDef = `
+// FilterProblem is an RFC 7807 problem document describing invalid filters, see NewFilterProblem.
+type FilterProblem struct {
+	Type          string               ` + "`json:\"type\"`" + `
+	Title         string               ` + "`json:\"title\"`" + `
+	Status        int                  ` + "`json:\"status\"`" + `
+	Detail        string               ` + "`json:\"detail,omitempty\"`" + `
+	Instance      string               ` + "`json:\"instance,omitempty\"`" + `
+	InvalidParams []FilterInvalidParam ` + "`json:\"invalid-params,omitempty\"`" + `
+}
+
+// FilterInvalidParam describes a single invalid query value of a FilterProblem.
+type FilterInvalidParam struct {
+	// Name is the query key.
+	Name string ` + "`json:\"name\"`" + `
+	// Reason is the message for users, see FilterMessages.
+	Reason string ` + "`json:\"reason\"`" + `
+	// Code is the violated rule, stable across versions and languages.
+	Code  FilterConstraint ` + "`json:\"code\"`" + `
+	Value string           ` + "`json:\"value,omitempty\"`" + `
+	Limit string           ` + "`json:\"limit,omitempty\"`" + `
+}
+
+// FilterMessages are the reasons of invalid params by the violated rule, e.g. in the language of
+// the user. {key}, {field}, {value} and {limit} are replaced with those of the FilterError,
+// rules without a message are described by the error text.
+type FilterMessages map[FilterConstraint]string
+
+func (m FilterMessages) reason(fe *FilterError) string {
+	msg, ok := m[fe.Constraint]
+	if !ok {
+		return fe.Error()
+	}
+	return strings.NewReplacer("{key}", fe.Key, "{field}", fe.Field, "{value}", fe.Value, "{limit}", fe.Limit).Replace(msg)
+}
+
+// NewFilterProblem converts an error returned by ParseFilters into a 400 Bad Request problem with
+// an invalid param per FilterError. Other errors are only described by the detail.
+// messages may be nil.
+func NewFilterProblem(err error, messages FilterMessages) *FilterProblem {
+	p := &FilterProblem{Type: "about:blank", Title: http.StatusText(http.StatusBadRequest), Status: http.StatusBadRequest}
+	var errs FilterErrors
+	if !errors.As(err, &errs) {
+		p.Detail = err.Error()
+		return p
+	}
+	for _, fe := range errs {
+		p.InvalidParams = append(p.InvalidParams, FilterInvalidParam{
+			Name:   fe.Key,
+			Reason: messages.reason(fe),
+			Code:   fe.Constraint,
+			Value:  fe.Value,
+			Limit:  fe.Limit,
+		})
+	}
+	return p
+}
+
+// FilterProblemResponder returns a FilterErrorResponder answering with application/problem+json,
+// see NewFilterProblem. messages picks the messages for the request, e.g. by its Accept-Language
+// header, and may be nil.
+func FilterProblemResponder(messages func(r *http.Request) FilterMessages) FilterErrorResponder {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		var m FilterMessages
+		if messages != nil {
+			m = messages(r)
+		}
+		p := NewFilterProblem(err, m)
+		p.Instance = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(p.Status)
+		_ = json.NewEncoder(w).Encode(p)
+	}
+}
+`
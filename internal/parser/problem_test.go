@@ -0,0 +1,65 @@
+package parser
+
+import (
+	goparser "go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_filterProblemDef(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	_, err := goparser.ParseFile(token.NewFileSet(), "problem.go", "package p\n"+filterProblemDef, 0)
+
+	// Assert
+	require.NoError(t, err)
+	require.Contains(t, filterProblemDef, "`json:\"invalid-params,omitempty\"`")
+}
+
+func TestFilterProblemResponder(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tName   string `ufi:\"kind=exact;key=name;maxlen=3\"`\n" +
+		"\tRating int    `ufi:\"kind=range;key=rating;min=1;max=5\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func main() {
+	const target = "/products?name=abcd&rating-from=9"
+	_, err := ParseFilters(target)
+	messages := func(*http.Request) FilterMessages {
+		return FilterMessages{FilterConstraintMax: "{key} must be at most {limit}"}
+	}
+	rec := httptest.NewRecorder()
+	FilterProblemResponder(messages)(rec, httptest.NewRequest(http.MethodGet, target, nil), err)
+	fmt.Println(rec.Code, rec.Header().Get("Content-Type"))
+	fmt.Print(rec.Body.String())
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, "400 application/problem+json\n", got[:strings.Index(got, "{")])
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"instance": "/products?name=abcd&rating-from=9",
+		"invalid-params": [
+			{"name": "name", "reason": "filter \"name\" (field Name): value \"abcd\" violates maxlen=3", "code": "maxlen", "value": "abcd", "limit": "3"},
+			{"name": "rating-from", "reason": "rating-from must be at most 5", "code": "max", "value": "9", "limit": "5"}
+		]
+	}`, got[strings.Index(got, "{"):])
+}
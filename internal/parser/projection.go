@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// _fieldsKey is the query key of the fields parameter.
+const _fieldsKey = "fields"
+
+func projectionName(origStructName string) string {
+	return origStructName + "Projection"
+}
+
+// selectName returns the name the fields parameter selects the field by: the name given with
+// the select option or the key of the field.
+func selectName(field _field) string {
+	if field._qf._selectName != "" {
+		return field._qf._selectName
+	}
+	return field._qf._key
+}
+
+// selectableFields returns the fields with the select option, in struct order.
+func selectableFields(fields []_field) []_field {
+	var res []_field
+	for _, field := range fields {
+		if field._qf._select {
+			res = append(res, field)
+		}
+	}
+	return res
+}
+
+// validateSelect checks the select options: every selectable field needs a unique name, and no
+// filter may read the key of the fields parameter.
+func validateSelect(fields []_field) error {
+	selectable := selectableFields(fields)
+	if len(selectable) == 0 {
+		return nil
+	}
+	names := make(map[string]string)
+	for _, field := range selectable {
+		name := selectName(field)
+		switch {
+		case hasKind(field, _qfKindGeo):
+			return fmt.Errorf("field %s: select option is not supported for the geo kind", field._originalName)
+		case name == "":
+			return fmt.Errorf("field %s: select option requires a name, e.g. select=%s, when the field has no key", field._originalName, snakeCase(field._originalName))
+		case strings.ContainsAny(name, ", "):
+			return fmt.Errorf("field %s: invalid select name %q", field._originalName, name)
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("fields %s and %s are both selected as %q", other, field._originalName, name)
+		}
+		names[name] = field._originalName
+	}
+	for _, field := range fields {
+		if len(field._qf._kindList) > 0 && field._qf._key == _fieldsKey {
+			return fmt.Errorf("field %s: key %q is taken by the fields parameter", field._originalName, _fieldsKey)
+		}
+	}
+	return nil
+}
+
+// generateFieldsParser generates the statement reading the fields parameter, empty when no field
+// is selectable.
+func generateFieldsParser(fields []_field) string {
+	if len(selectableFields(fields)) == 0 {
+		return ""
+	}
+	return `if q.Has(_filterFieldsKey) {
+	res._fields = _filterParseFields(q.Get(_filterFieldsKey), &errs)
+}`
+}
+
+// generateProjection generates the projection of the filtered struct to the fields selected with
+// the fields parameter, as a reduced struct, a map and SQL columns. It is empty when no field is
+// selectable.
+func generateProjection(structRcv, structName, origStructName string, fields []_field) string {
+	const tmpl = `
+// _filterSelectNames are the names the fields parameter accepts, in struct order.
+var _filterSelectNames = []string{$names}
+
+// _filterSelectColumns are the SQL columns of the fields of _filterSelectNames.
+var _filterSelectColumns = []string{$columns}
+
+// $typeName is a $origStruct reduced to the fields selected with the fields parameter, see Project.
+// Fields are named in JSON like in the fields parameter, fields that are not selected are left out.
+type $typeName struct {
+	$fields
+}
+
+// Fields returns the names of the fields selected with the fields parameter in struct order, nil
+// when it was not sent and every field is selected.
+func ($rcv *$structName) Fields() []string {
+	return slices.Clone($rcv._fields)
+}
+
+func ($rcv *$structName) selects(name string) bool {
+	return len($rcv._fields) == 0 || slices.Contains($rcv._fields, name)
+}
+
+// Project returns the selected fields of v. Fields behind nil pointers are left out.
+func ($rcv *$structName) Project(v *$origStruct) $typeName {
+	var res $typeName
+	$toStruct
+	return res
+}
+
+// ProjectMap returns the selected fields of v by name. Fields behind nil pointers are left out.
+func ($rcv *$structName) ProjectMap(v *$origStruct) map[string]any {
+	res := make(map[string]any, len(_filterSelectNames))
+	$toMap
+	return res
+}
+
+// Columns returns the SQL columns of the selected fields, in the order of Fields, for a SELECT list
+// such as strings.Join(f.Columns(), ", ").
+func ($rcv *$structName) Columns() []string {
+	var res []string
+	for i, name := range _filterSelectNames {
+		if $rcv.selects(name) {
+			res = append(res, _filterSelectColumns[i])
+		}
+	}
+	return res
+}
+`
+	var names, columns, structFields, project, projectMap []string
+	for _, field := range selectableFields(fields) {
+		name := strconv.Quote(selectName(field))
+		names = append(names, name)
+		columns = append(columns, strconv.Quote(fieldColumn(field)))
+		structFields = append(structFields, fmt.Sprintf("%s *%s `json:\"%s,omitempty\"`", field._originalName, fieldValueType(field), selectName(field)))
+		read := fmt.Sprintf("if %s.selects(%s) {\n\tif x, ok := %s(v); ok {\n\t\t%%s\n\t}\n}", structRcv, name, fieldValueFuncName(field))
+		project = append(project, fmt.Sprintf(read, "res."+field._originalName+" = &x"))
+		projectMap = append(projectMap, fmt.Sprintf(read, "res["+name+"] = x"))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$names":      strings.Join(names, ", "),
+		"$columns":    strings.Join(columns, ", "),
+		"$typeName":   projectionName(origStructName),
+		"$origStruct": origStructName,
+		"$fields":     strings.Join(structFields, "\n"),
+		"$rcv":        structRcv,
+		"$structName": structName,
+		"$toMap":      strings.Join(projectMap, "\n"),
+		"$toStruct":   strings.Join(project, "\n"),
+	})
+}
+
+const filterProjectionFuncs = `
+// _filterFieldsKey is the query key selecting fields, e.g. fields=name,price.
+const _filterFieldsKey = "fields"
+
+// _filterParseFields returns the names of the fields selected by raw, a comma separated list, in
+// struct order. Unknown names are added to errs.
+func _filterParseFields(raw string, errs *FilterErrors) []string {
+	requested := strings.Split(raw, ",")
+	for i, name := range requested {
+		requested[i] = strings.TrimSpace(name)
+		if requested[i] != "" && !slices.Contains(_filterSelectNames, requested[i]) {
+			errs.add(&FilterError{Key: _filterFieldsKey, Constraint: FilterConstraintFields, Limit: strings.Join(_filterSelectNames, ","), Value: requested[i]})
+		}
+	}
+	var res []string
+	for _, name := range _filterSelectNames {
+		if slices.Contains(requested, name) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+`
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateSelect(t *testing.T) {
+	t.Parallel()
+
+	exact := []queryFilterKind{_qfKindExact}
+	tests := []struct {
+		name    string
+		fields  []_field
+		wantErr bool
+	}{
+		{name: "key", fields: []_field{{_originalName: "Name", _qf: utiQueryFilter{_kindList: exact, _key: "name", _select: true}}}},
+		{name: "named", fields: []_field{{_originalName: "Title", _qf: utiQueryFilter{_select: true, _selectName: "title"}}}},
+		{name: "no name", fields: []_field{{_originalName: "Title", _qf: utiQueryFilter{_select: true}}}, wantErr: true},
+		{name: "duplicate", fields: []_field{
+			{_originalName: "Name", _qf: utiQueryFilter{_kindList: exact, _key: "name", _select: true}},
+			{_originalName: "Title", _qf: utiQueryFilter{_select: true, _selectName: "name"}},
+		}, wantErr: true},
+		{name: "fields key", fields: []_field{
+			{_originalName: "Name", _qf: utiQueryFilter{_kindList: exact, _key: "name", _select: true}},
+			{_originalName: "Fields", _qf: utiQueryFilter{_kindList: exact, _key: "fields"}},
+		}, wantErr: true},
+		{name: "geo", fields: []_field{{_originalName: "Lat", _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindGeo}, _select: true, _selectName: "lat"}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		// Act
+		err := validateSelect(test.fields)
+
+		// Assert
+		require.Equal(t, test.wantErr, err != nil, test.name)
+	}
+}
+
+func Test_generateProjection(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "Name",
+		_goType:       "string",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact}, _key: "name", _select: true},
+	}, {
+		_originalName: "Price",
+		_goType:       "float64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "price"},
+	}, {
+		_originalName: "Title",
+		_goType:       "string",
+		_qf:           utiQueryFilter{_select: true, _selectName: "title", _column: "headline"},
+	}}
+
+	// Act
+	got := generateProjection("_Pr", "_ProductFilter", "Product", fields)
+
+	// Assert
+	require.Contains(t, got, `var _filterSelectNames = []string{"name", "title"}`)
+	require.Contains(t, got, `var _filterSelectColumns = []string{"name", "headline"}`)
+	require.Contains(t, got, "type ProductProjection struct {\n\tName *string `json:\"name,omitempty\"`\nTitle *string `json:\"title,omitempty\"`\n}")
+	require.Contains(t, got, "if _Pr.selects(\"title\") {\n\tif x, ok := _TitleValue(v); ok {\n\t\tres.Title = &x\n\t}\n}")
+	require.Contains(t, got, "if _Pr.selects(\"name\") {\n\tif x, ok := _NameValue(v); ok {\n\t\tres[\"name\"] = x\n\t}\n}")
+	require.NotEmpty(t, generateFieldsParser(fields))
+
+	// Act
+	got = generateProjection("_Pr", "_ProductFilter", "Product", fields[1:2])
+
+	// Assert
+	require.Empty(t, got)
+	require.Empty(t, generateFieldsParser(fields[1:2]))
+}
+
+func TestProjection(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tName string `ufi:\"kind=exact;key=name;select\"`\n" +
+		"\tPrice float64 `ufi:\"kind=range;key=price;select;column=list_price\"`\n" +
+		"\tSecret string\n" +
+		"\tSeller *Seller `ufi:\"key=seller\"`\n}\n\n" +
+		"type Seller struct {\n" +
+		"\tCountry string `ufi:\"kind=exact;key=country;select\"`\n}\n"
+	const main = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	items := []Product{
+		{Name: "a", Price: 1.5, Secret: "x", Seller: &Seller{Country: "de"}},
+		{Name: "b", Price: 2, Secret: "y"},
+	}
+	for _, query := range []string{"", "fields=seller.country,name", "fields=price", "fields=name,secret"} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(query, f.Fields(), f.Columns())
+		for i := range items {
+			data, err := json.Marshal(f.Project(&items[i]))
+			if err != nil {
+				panic(err)
+			}
+			fmt.Println(string(data), f.ProjectMap(&items[i]))
+		}
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	// the nil seller is left out
+	require.Equal(t, ` [] [name list_price seller_country]
+{"name":"a","price":1.5,"seller.country":"de"} map[name:a price:1.5 seller.country:de]
+{"name":"b","price":2} map[name:b price:2]
+fields=seller.country,name [name seller.country] [name seller_country]
+{"name":"a","seller.country":"de"} map[name:a seller.country:de]
+{"name":"b"} map[name:b]
+fields=price [price] [list_price]
+{"price":1.5} map[price:1.5]
+{"price":2} map[price:2]
+filter "fields": unknown field "secret", allowed: name,price,seller.country
+`, got)
+}
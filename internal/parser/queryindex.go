@@ -0,0 +1,438 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+func queryIndexName(origStructName string) string {
+	return origStructName + "Index"
+}
+
+// generateQueryIndex generates an index of a slice of the filtered struct, answering filters
+// without scanning it. Fields are indexed like in the subscription index: values of exact and
+// multi-value fields in hash indexes, ordered values in sorted indexes.
+func generateQueryIndex(structName, origStructName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// $queryIndex holds $origStruct items under IDs and answers filters without scanning them: values of exact
+// and multi-value fields are kept in hash indexes, values of range fields in sorted indexes. Search
+// orders the indexed conditions of a filter by the number of items they hit and intersects their
+// bitmaps, most selective first. Once few items are left, they are checked with Match instead, as are
+// the conditions that are not indexed. Items are inserted and deleted incrementally, sorted indexes
+// take the changes in once before the next search. It is safe for concurrent use.
+type $queryIndex struct {
+	mu    sync.RWMutex
+	items []$origStruct
+	live  _filterBitmap
+	// dead holds the IDs of deleted items that sorted indexes still hold, they are reused after
+	// the next flush. free holds the IDs that can be reused.
+	dead  []int
+	free  []int
+	dirty bool
+	$indexFields
+}
+
+// New$queryIndex indexes items, their IDs are their positions.
+func New$queryIndex(items []$origStruct) *$queryIndex {
+	ix := &$queryIndex{
+		items: make([]$origStruct, 0, len(items)),
+		$initFields
+	}
+	for _, v := range items {
+		ix.insert(v)
+	}
+	return ix
+}
+
+// Insert adds v and returns its ID. IDs of deleted items are reused.
+func (ix *$queryIndex) Insert(v $origStruct) int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.insert(v)
+}
+
+func (ix *$queryIndex) insert(v $origStruct) int {
+	var id int
+	if n := len(ix.free); n > 0 {
+		id, ix.free = ix.free[n-1], ix.free[:n-1]
+		ix.items[id] = v
+	} else {
+		id = len(ix.items)
+		ix.items = append(ix.items, v)
+	}
+	ix.live.set(id)
+	ix.index(&ix.items[id], id, true)
+	ix.dirty = true
+	return id
+}
+
+// Delete removes the item with the ID and reports whether there was one.
+func (ix *$queryIndex) Delete(id int) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if id < 0 || !ix.live.has(id) {
+		return false
+	}
+	ix.index(&ix.items[id], id, false)
+	ix.live.clear(id)
+	ix.items[id] = $origStruct{}
+	ix.dead = append(ix.dead, id)
+	ix.dirty = true
+	return true
+}
+
+// Get returns the item with the ID.
+func (ix *$queryIndex) Get(id int) ($origStruct, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if id < 0 || !ix.live.has(id) {
+		return $origStruct{}, false
+	}
+	return ix.items[id], true
+}
+
+// Len returns the number of items.
+func (ix *$queryIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.live.n
+}
+
+// Search returns the IDs of the items that pass the filters, in ascending order.
+func (ix *$queryIndex) Search(f *$structName) []int {
+	ix.rlock()
+	defer ix.mu.RUnlock()
+	return ix.search(f)
+}
+
+// Apply returns the items that pass the filters, like $structName.Apply does without the index.
+func (ix *$queryIndex) Apply(f *$structName) []$origStruct {
+	ix.rlock()
+	defer ix.mu.RUnlock()
+	ids := ix.search(f)
+	res := make([]$origStruct, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, ix.items[id])
+	}
+	return res
+}
+
+// rlock read locks ix once the sorted indexes hold every change.
+func (ix *$queryIndex) rlock() {
+	for {
+		ix.mu.RLock()
+		if !ix.dirty {
+			return
+		}
+		ix.mu.RUnlock()
+		ix.mu.Lock()
+		ix.flush()
+		ix.mu.Unlock()
+	}
+}
+
+func (ix *$queryIndex) flush() {
+	if !ix.dirty {
+		return
+	}
+	var dead _filterBitmap
+	for _, id := range ix.dead {
+		dead.set(id)
+	}
+	$flushes
+	ix.free = append(ix.free, ix.dead...)
+	ix.dead = ix.dead[:0]
+	ix.dirty = false
+}
+
+// index adds v, the item with the ID, to the hash and sorted indexes or, when add is false, removes
+// it from the hash indexes. Sorted indexes drop deleted items on flush.
+func (ix *$queryIndex) index(v *$origStruct, id int, add bool) {
+	$indexValues
+}
+
+func (ix *$queryIndex) search(f *$structName) []int {
+	var conds []_filterIndexCond
+	$conds
+	residual := $residual
+	slices.SortFunc(conds, func(a, b _filterIndexCond) int {
+		return cmp.Compare(a.estimate, b.estimate)
+	})
+	hits := ix.live.clone()
+	for _, c := range conds {
+		if hits.n == 0 {
+			break
+		}
+		if hits.n*_filterMatchCost < c.estimate {
+			// matching the few hits left is cheaper than reading the hits of the condition
+			residual = true
+			break
+		}
+		var b _filterBitmap
+		c.hits(&b)
+		hits.and(&b)
+	}
+	res := make([]int, 0, hits.n)
+	hits.each(func(id int) {
+		if !residual || f.Match(&ix.items[id]) {
+			res = append(res, id)
+		}
+	})
+	return res
+}
+`
+	var indexFields, initFields, flushes, indexValues, conds, residual []string
+	for _, field := range fields {
+		pfs := structFieldMap[field._originalName]
+		if len(pfs) == 0 {
+			continue
+		}
+		buckets, intervals := subscriptionIndexing(field)
+		hashName, sortedName := "_"+field._originalName+"Hash", "_"+field._originalName+"Sorted"
+		var puts []string
+		if buckets {
+			indexFields = append(indexFields, fmt.Sprintf("%s _filterHashIndex[%s]", hashName, field._goType))
+			initFields = append(initFields, fmt.Sprintf("%s: make(_filterHashIndex[%s]),", hashName, field._goType))
+			puts = append(puts, fmt.Sprintf("ix.%s.put(x, id, add)", hashName))
+		}
+		if intervals {
+			indexFields = append(indexFields, fmt.Sprintf("%s *_filterSortedIndex[%s]", sortedName, field._goType))
+			initFields = append(initFields, fmt.Sprintf("%s: &_filterSortedIndex[%s]{cmp: %s},", sortedName, field._goType, generateCompareFunc(field)))
+			puts = append(puts, fmt.Sprintf("if add {\n\tix.%s.add(x, id)\n}", sortedName))
+			flushes = append(flushes, fmt.Sprintf("ix.%s.flush(&dead)", sortedName))
+		}
+		if len(puts) > 0 {
+			indexValues = append(indexValues, fmt.Sprintf("if x, ok := %s(v); ok {\n\t%s\n}", fieldValueFuncName(field), strings.Join(puts, "\n")))
+		}
+
+		var rangeDone bool
+		for _, pf := range pfs {
+			filter := "f." + pf._name
+			var cond string
+			switch {
+			case (pf.isRangeGte || pf.isRangeLte) && intervals:
+				if rangeDone {
+					continue
+				}
+				rangeDone = true
+				gte, lte := "f._"+field._originalName+"Gte", "f._"+field._originalName+"Lte"
+				conds = append(conds, fmt.Sprintf("if %s != nil || %s != nil {\n\tconds = append(conds, ix.%s.cond(%s, %s))\n}",
+					gte, lte, sortedName, gte, lte))
+				continue
+			case pf._kind == _qfKindExact && buckets:
+				cond = fmt.Sprintf("ix.%s.cond([]%s{*%s})", hashName, field._goType, filter)
+			case pf._kind == _qfKindMultiValue && buckets:
+				cond = fmt.Sprintf("ix.%s.cond(*%s)", hashName, filter)
+			case pf._kind == _qfKindExact && intervals:
+				cond = fmt.Sprintf("ix.%s.condEach([]%s{*%s})", sortedName, field._goType, filter)
+			case pf._kind == _qfKindMultiValue && intervals:
+				cond = fmt.Sprintf("ix.%s.condEach(*%s)", sortedName, filter)
+			default:
+				residual = append(residual, filter+" != nil")
+				continue
+			}
+			conds = append(conds, fmt.Sprintf("if %s != nil {\n\tconds = append(conds, %s)\n}", filter, cond))
+		}
+	}
+	if len(residual) == 0 {
+		residual = []string{"false"}
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$queryIndex":  queryIndexName(origStructName),
+		"$origStruct":  origStructName,
+		"$structName":  structName,
+		"$indexFields": strings.Join(indexFields, "\n"),
+		"$initFields":  strings.Join(initFields, "\n"),
+		"$flushes":     strings.Join(flushes, "\n"),
+		"$indexValues": strings.Join(indexValues, "\n"),
+		"$conds":       strings.Join(conds, "\n"),
+		"$residual":    strings.Join(residual, " || "),
+	})
+}
+
+const filterQueryIndexFuncs = `
+// _filterMatchCost is about how many times matching an item costs more than reading its ID from an index.
+const _filterMatchCost = 16
+
+// _filterBitmap is a set of item IDs.
+type _filterBitmap struct {
+	words []uint64
+	// n is the number of IDs in the set.
+	n int
+}
+
+func (b *_filterBitmap) set(id int) {
+	w := id / 64
+	if w >= len(b.words) {
+		b.words = append(b.words, make([]uint64, w+1-len(b.words))...)
+	}
+	if bit := uint64(1) << (id % 64); b.words[w]&bit == 0 {
+		b.words[w] |= bit
+		b.n++
+	}
+}
+
+func (b *_filterBitmap) clear(id int) {
+	if w, bit := id/64, uint64(1)<<(id%64); w < len(b.words) && b.words[w]&bit != 0 {
+		b.words[w] &^= bit
+		b.n--
+	}
+}
+
+func (b *_filterBitmap) has(id int) bool {
+	w := id / 64
+	return w < len(b.words) && b.words[w]&(1<<(id%64)) != 0
+}
+
+func (b *_filterBitmap) clone() _filterBitmap {
+	return _filterBitmap{words: slices.Clone(b.words), n: b.n}
+}
+
+// and keeps the IDs that o holds too.
+func (b *_filterBitmap) and(o *_filterBitmap) {
+	b.n = 0
+	for i := range b.words {
+		if i < len(o.words) {
+			b.words[i] &= o.words[i]
+		} else {
+			b.words[i] = 0
+		}
+		b.n += bits.OnesCount64(b.words[i])
+	}
+}
+
+// each calls visit with the IDs in ascending order.
+func (b *_filterBitmap) each(visit func(id int)) {
+	for w, word := range b.words {
+		for word != 0 {
+			visit(w*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
+
+// _filterIndexCond is an indexed condition of a filter: hits adds the IDs of the items passing it,
+// about estimate of them.
+type _filterIndexCond struct {
+	estimate int
+	hits     func(b *_filterBitmap)
+}
+
+// _filterHashIndex holds the IDs of items by the value of a field.
+type _filterHashIndex[T comparable] map[T][]int
+
+func (h _filterHashIndex[T]) put(x T, id int, add bool) {
+	if add {
+		h[x] = append(h[x], id)
+		return
+	}
+	ids := h[x]
+	if i := slices.Index(ids, id); i >= 0 {
+		ids[i] = ids[len(ids)-1]
+		ids = ids[:len(ids)-1]
+	}
+	if len(ids) == 0 {
+		delete(h, x)
+		return
+	}
+	h[x] = ids
+}
+
+// cond returns the condition passed by items holding any of values.
+func (h _filterHashIndex[T]) cond(values []T) _filterIndexCond {
+	estimate := 0
+	for _, v := range values {
+		estimate += len(h[v])
+	}
+	return _filterIndexCond{estimate: estimate, hits: func(b *_filterBitmap) {
+		for _, v := range values {
+			for _, id := range h[v] {
+				b.set(id)
+			}
+		}
+	}}
+}
+
+type _filterSortedEntry[T any] struct {
+	value T
+	id    int
+}
+
+// _filterSortedIndex holds the IDs of items ordered by the value of a field. Added items are pending
+// until flush merges them in.
+type _filterSortedIndex[T any] struct {
+	cmp     func(a, b T) int
+	entries []_filterSortedEntry[T]
+	pending []_filterSortedEntry[T]
+}
+
+func (s *_filterSortedIndex[T]) add(x T, id int) {
+	s.pending = append(s.pending, _filterSortedEntry[T]{value: x, id: id})
+}
+
+// flush drops the entries of dead items and merges the pending entries in.
+func (s *_filterSortedIndex[T]) flush(dead *_filterBitmap) {
+	if dead.n > 0 {
+		isDead := func(e _filterSortedEntry[T]) bool { return dead.has(e.id) }
+		s.entries = slices.DeleteFunc(s.entries, isDead)
+		s.pending = slices.DeleteFunc(s.pending, isDead)
+	}
+	if len(s.pending) == 0 {
+		return
+	}
+	byValue := func(a, b _filterSortedEntry[T]) int { return s.cmp(a.value, b.value) }
+	slices.SortFunc(s.pending, byValue)
+	merged := make([]_filterSortedEntry[T], 0, len(s.entries)+len(s.pending))
+	i, j := 0, 0
+	for i < len(s.entries) && j < len(s.pending) {
+		if byValue(s.pending[j], s.entries[i]) < 0 {
+			merged = append(merged, s.pending[j])
+			j++
+		} else {
+			merged = append(merged, s.entries[i])
+			i++
+		}
+	}
+	merged = append(append(merged, s.entries[i:]...), s.pending[j:]...)
+	s.entries, s.pending = merged, s.pending[:0]
+}
+
+// bounds returns the positions of the entries from lo to hi, both inclusive, nil bounds are unbounded.
+func (s *_filterSortedIndex[T]) bounds(lo, hi *T) (int, int) {
+	i, j := 0, len(s.entries)
+	if lo != nil {
+		i = sort.Search(len(s.entries), func(k int) bool { return s.cmp(s.entries[k].value, *lo) >= 0 })
+	}
+	if hi != nil {
+		j = sort.Search(len(s.entries), func(k int) bool { return s.cmp(s.entries[k].value, *hi) > 0 })
+	}
+	return i, max(i, j)
+}
+
+// cond returns the condition passed by items with values from lo to hi.
+func (s *_filterSortedIndex[T]) cond(lo, hi *T) _filterIndexCond {
+	i, j := s.bounds(lo, hi)
+	return _filterIndexCond{estimate: j - i, hits: func(b *_filterBitmap) {
+		for _, e := range s.entries[i:j] {
+			b.set(e.id)
+		}
+	}}
+}
+
+// condEach returns the condition passed by items holding any of values.
+func (s *_filterSortedIndex[T]) condEach(values []T) _filterIndexCond {
+	estimate := 0
+	for i := range values {
+		lo, hi := s.bounds(&values[i], &values[i])
+		estimate += hi - lo
+	}
+	return _filterIndexCond{estimate: estimate, hits: func(b *_filterBitmap) {
+		for i := range values {
+			lo, hi := s.bounds(&values[i], &values[i])
+			for _, e := range s.entries[lo:hi] {
+				b.set(e.id)
+			}
+		}
+	}}
+}
+`
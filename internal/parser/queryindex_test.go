@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateQueryIndex(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "Price",
+		_goType:       "float64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange, _qfKindExact}, _key: "price"},
+	}, {
+		_originalName: "Created",
+		_goType:       "time.Time",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindMultiValue}, _key: "created"},
+	}, {
+		_originalName: "Tags",
+		_goType:       "string",
+		_slice:        true,
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindAny}, _key: "tags"},
+	}}
+	_, fieldMap := generateFilterStructDef("_ProductFilter", fields)
+
+	// Act
+	got := generateQueryIndex("_ProductFilter", "Product", fields, fieldMap)
+
+	// Assert
+	require.Contains(t, got, "type ProductIndex struct {")
+	require.Contains(t, got, "_PriceHash _filterHashIndex[float64]\n_PriceSorted *_filterSortedIndex[float64]\n_CreatedSorted *_filterSortedIndex[time.Time]")
+	require.Contains(t, got, "if x, ok := _PriceValue(v); ok {\n\tix._PriceHash.put(x, id, add)\nif add {\n\tix._PriceSorted.add(x, id)\n}\n}")
+	require.Contains(t, got, "if f._PriceGte != nil || f._PriceLte != nil {\n\tconds = append(conds, ix._PriceSorted.cond(f._PriceGte, f._PriceLte))\n}")
+	require.Contains(t, got, "if f._PriceExact != nil {\n\tconds = append(conds, ix._PriceHash.cond([]float64{*f._PriceExact}))\n}")
+	require.Contains(t, got, "conds = append(conds, ix._CreatedSorted.condEach(*f._CreatedMultiValue))")
+	require.Contains(t, got, "ix._PriceSorted.flush(&dead)\nix._CreatedSorted.flush(&dead)")
+	require.Contains(t, got, "residual := f._TagsAny != nil")
+}
+
+func TestQueryIndex(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tPrice float64  `ufi:\"kind=range,exact;key=price\"`\n" +
+		"\tName  string   `ufi:\"kind=exact,multi-value;key=name\"`\n" +
+		"\tStock int      `ufi:\"kind=range;key=stock\"`\n" +
+		"\tTags  []string `ufi:\"kind=any;key=tags\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+)
+
+func parse(query string) *_ProductFilter {
+	f, err := ParseFiltersQuery(query)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func main() {
+	ix := NewProductIndex([]Product{
+		{Price: 5, Name: "a", Stock: 1},
+		{Price: 15, Name: "a", Stock: 10, Tags: []string{"x"}},
+		{Price: 15, Name: "b", Stock: 20},
+		{Price: 25, Name: "a", Stock: 30},
+	})
+	for _, query := range []string{"", "price-from=10&price-to=20", "price-from=10&name=a", "price=15&stock-from=15", "name=a,b&tags-any=x", "name=c"} {
+		fmt.Printf("%q %v\n", query, ix.Search(parse(query)))
+	}
+
+	// deleted before the sorted indexes take the insert in
+	id := ix.Insert(Product{Price: 15, Name: "a", Stock: 15})
+	fmt.Println(id, ix.Delete(id), ix.Delete(id), ix.Len(), ix.Search(parse("price-from=10&price-to=20")))
+	// deleted after that, the ID is reused once the next search flushed the delete
+	fmt.Println(ix.Delete(1), ix.Search(parse("price-from=10&price-to=20")), ix.Insert(Product{Price: 16, Name: "c"}))
+	_, ok := ix.Get(1)
+	fmt.Println(ok, ix.Search(parse("price-from=10&price-to=20")), ix.Search(parse("name=c")))
+
+	// the index agrees with Apply on the same items
+	rnd := rand.New(rand.NewPCG(1, 2))
+	item := func() Product {
+		return Product{Price: float64(rnd.IntN(100)), Name: fmt.Sprintf("n%d", rnd.IntN(20)), Stock: rnd.IntN(1000), Tags: []string{fmt.Sprintf("t%d", rnd.IntN(5))}}
+	}
+	queries := []string{"price-from=%d", "price-to=%d&stock-from=500", "price-from=%d&price-to=%[1]d5&name=n1,n2,n3", "price=%d&stock-from=100", "name=n%d", "stock-from=%d0&stock-to=%[1]d5", "tags-any=t%d&price-to=90"}
+	items := make([]Product, 3000)
+	for i := range items {
+		items[i] = item()
+	}
+	ix = NewProductIndex(items)
+	live := make(map[int]bool, len(items))
+	for i := range items {
+		live[i] = true
+	}
+	mismatches := 0
+	for range 300 {
+		for range 20 {
+			if id := rnd.IntN(len(items)); live[id] && rnd.IntN(2) == 0 {
+				ix.Delete(id)
+				live[id] = false
+			} else {
+				v := item()
+				id := ix.Insert(v)
+				for id >= len(items) {
+					items = append(items, Product{})
+				}
+				items[id], live[id] = v, true
+			}
+		}
+		var current []Product
+		for id, v := range items {
+			if live[id] {
+				current = append(current, v)
+			}
+		}
+		f := parse(fmt.Sprintf(queries[rnd.IntN(len(queries))], rnd.IntN(10)))
+		if want, got := f.Apply(current), ix.Apply(f); len(want) != len(got) || len(want) > 0 && !reflect.DeepEqual(want, got) {
+			mismatches++
+		}
+	}
+	n := 0
+	for _, ok := range live {
+		if ok {
+			n++
+		}
+	}
+	fmt.Println("mismatches", mismatches, ix.Len() == n)
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `"" [0 1 2 3]
+"price-from=10&price-to=20" [1 2]
+"price-from=10&name=a" [1 3]
+"price=15&stock-from=15" [2]
+"name=a,b&tags-any=x" [1]
+"name=c" []
+4 true false 4 [1 2]
+true [2] 1
+true [1 2] [1]
+mismatches 0 true
+`, got)
+}
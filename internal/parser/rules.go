@@ -0,0 +1,404 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _directivePrefix = "//ufi:"
+
+type structRuleKind string
+
+const (
+	_ruleExclusive = structRuleKind("exclusive")
+	_ruleTogether  = structRuleKind("together")
+	_ruleMaxSpan   = structRuleKind("maxspan")
+	_ruleVersion   = structRuleKind("version")
+)
+
+// _structRule is a struct level rule declared with a directive comment next to the go:generate line:
+//
+//	//ufi:exclusive q,name
+//	//ufi:together lat,lon
+//	//ufi:maxspan created 90d
+//	//ufi:version 2
+type _structRule struct {
+	_kind    structRuleKind
+	_keys    []string
+	_span    string
+	_version int
+}
+
+// parseStructRules collects directives from the comment block around goLine (1-based). When goLine
+// is unknown, they are collected from the comment blocks around every go:generate line of the file,
+// other comments may mention directives without declaring them.
+func parseStructRules(sourceRows []string, goLine int) ([]_structRule, error) {
+	isComment := func(i int) bool {
+		return strings.HasPrefix(strings.TrimSpace(sourceRows[i]), "//")
+	}
+
+	var goLines []int
+	if goLine > 0 && goLine <= len(sourceRows) {
+		goLines = append(goLines, goLine-1)
+	} else {
+		for i, row := range sourceRows {
+			if strings.HasPrefix(strings.TrimSpace(row), "//go:generate ") {
+				goLines = append(goLines, i)
+			}
+		}
+	}
+
+	var rules []_structRule
+	read := 0
+	for _, line := range goLines {
+		if line < read {
+			// the line is in the block of the previous go:generate line
+			continue
+		}
+		from, to := line, line+1
+		for from > 0 && isComment(from-1) {
+			from--
+		}
+		for to < len(sourceRows) && isComment(to) {
+			to++
+		}
+		read = to
+		for _, row := range sourceRows[from:to] {
+			row = strings.TrimSpace(row)
+			if !strings.HasPrefix(row, _directivePrefix) {
+				continue
+			}
+			rule, err := parseDirective(strings.TrimPrefix(row, _directivePrefix))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", row, err)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func parseDirective(s string) (_structRule, error) {
+	args := strings.Fields(s)
+	if len(args) == 0 {
+		return _structRule{}, fmt.Errorf("empty directive")
+	}
+
+	rule := _structRule{_kind: structRuleKind(args[0])}
+	switch rule._kind {
+	case _ruleExclusive, _ruleTogether:
+		if len(args) != 2 {
+			return _structRule{}, fmt.Errorf("expected comma separated keys")
+		}
+		rule._keys = strings.Split(args[1], ",")
+		if len(rule._keys) < 2 {
+			return _structRule{}, fmt.Errorf("expected at least two keys")
+		}
+	case _ruleMaxSpan:
+		if len(args) != 3 {
+			return _structRule{}, fmt.Errorf("expected key and span")
+		}
+		rule._keys = []string{args[1]}
+		rule._span = args[2]
+	case _ruleVersion:
+		if len(args) != 2 {
+			return _structRule{}, fmt.Errorf("expected schema version")
+		}
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 1 {
+			return _structRule{}, fmt.Errorf("invalid schema version %q, expected a positive integer", args[1])
+		}
+		rule._version = v
+	default:
+		return _structRule{}, fmt.Errorf("unknown directive %q", args[0])
+	}
+	return rule, nil
+}
+
+// _resolvedKey is a key referenced by a directive: either the key of a field,
+// which stands for all of its query keys, or a single query key.
+type _resolvedKey struct {
+	_name       string
+	_field      _field
+	_constNames []string
+}
+
+func resolveRuleKey(name string, fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) (_resolvedKey, error) {
+	for _, field := range fields {
+		res := _resolvedKey{_name: name, _field: field}
+		seen := make(map[string]struct{})
+		for _, pf := range structFieldMap[field._originalName] {
+			constName := qfConstKeyMap[pf]
+			if _, ok := seen[constName]; ok {
+				continue
+			}
+			seen[constName] = struct{}{}
+			if field._qf._key == name || queryKey(field, pf) == name {
+				res._constNames = append(res._constNames, constName)
+			}
+		}
+		if len(res._constNames) > 0 {
+			return res, nil
+		}
+	}
+	return _resolvedKey{}, fmt.Errorf("unknown filter key %q", name)
+}
+
+// queryKey returns the query key backing pf, see generateConstKeys.
+func queryKey(field _field, pf parserField) string {
+	switch {
+	case pf.isRangeGte:
+		return field._qf._key + "-from"
+	case pf.isRangeLte:
+		return field._qf._key + "-to"
+	case isSetKind(pf._kind):
+		return field._qf._key + "-" + string(pf._kind)
+	case pf._geo != "" && field._qf._key == "":
+		return string(pf._geo)
+	case pf._geo != "":
+		return field._qf._key + "-" + string(pf._geo)
+	case pf._kind == _qfKindInCIDR:
+		return field._qf._key + "-in"
+	}
+	return field._qf._key
+}
+
+func (k _resolvedKey) presentExpr() string {
+	return fmt.Sprintf("_filterKeysPresent(q, %s)", strings.Join(k._constNames, ", "))
+}
+
+// generatePresenceRuleChecks generates exclusive and together checks. They run against the query
+// as it was sent, so defaults never make keys conflict.
+func generatePresenceRuleChecks(rules []_structRule, fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) (string, error) {
+	const tmpl = `
+if $cond {
+	errs.add(&FilterError{Field: "$errField", Key: "$key", Constraint: $constraint, Limit: "$limit"})
+}
+`
+	var rows []string
+	for _, rule := range rules {
+		if rule._kind != _ruleExclusive && rule._kind != _ruleTogether {
+			continue
+		}
+		keys := make([]_resolvedKey, 0, len(rule._keys))
+		for _, name := range rule._keys {
+			key, err := resolveRuleKey(name, fields, structFieldMap, qfConstKeyMap)
+			if err != nil {
+				return "", fmt.Errorf("%s directive: %w", rule._kind, err)
+			}
+			keys = append(keys, key)
+		}
+
+		for i, key := range keys {
+			var conds []string
+			switch rule._kind {
+			case _ruleExclusive:
+				// every pair is reported once, by its latter key
+				for _, prev := range keys[:i] {
+					conds = append(conds, fmt.Sprintf("%s && %s", prev.presentExpr(), key.presentExpr()))
+				}
+			case _ruleTogether:
+				var others []string
+				for j, other := range keys {
+					if j != i {
+						others = append(others, other.presentExpr())
+					}
+				}
+				conds = append(conds, fmt.Sprintf("!%s && (%s)", key.presentExpr(), strings.Join(others, " || ")))
+			}
+			if len(conds) == 0 {
+				continue
+			}
+			rows = append(rows, namedReplace(tmpl, map[string]string{
+				"$cond":       strings.Join(conds, " || "),
+				"$errField":   fieldDisplayName(key._field),
+				"$key":        key._name,
+				"$constraint": ternary(rule._kind == _ruleExclusive, "FilterConstraintExclusive", "FilterConstraintTogether"),
+				"$limit":      strings.Join(rule._keys, ","),
+			}))
+		}
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return strings.Join(rows, ""), nil
+}
+
+const filterKeysPresentFunc = `
+func _filterKeysPresent(q url.Values, keys ...string) bool {
+	for _, key := range keys {
+		if q.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+`
+
+// generateRangeChecks generates checks that every range has its lower bound not greater
+// than its upper bound and, for maxspan directives, that the bounds are close enough. A default
+// conflicting with the bound sent in the query is dropped, so the sent bound wins, and spans are
+// only checked between bounds sent in the query.
+func generateRangeChecks(rules []_structRule, fields []_field, structFieldMap map[string][]parserField, qfConstKeyMap map[parserField]string) (string, error) {
+	const tmpl = `
+if res.$gteField != nil && res.$lteField != nil {
+	if $less {
+		$conflict
+	}$spanCheck
+}
+`
+	const conflictTmpl = `errs.add(&FilterError{Field: "$errField", Key: $gteKey, Constraint: FilterConstraintRange, Limit: q.Get($lteKey), Value: q.Get($gteKey)})`
+	const dropTmpl = `case res._defaulted[$key]:
+	res.$field = nil
+	delete(res._defaulted, $key)
+`
+	const spanTmpl = ` else if $notDefaulted$spanExceeded {
+		errs.add(&FilterError{Field: "$errField", Key: $lteKey, Constraint: FilterConstraintMaxSpan, Limit: "$maxSpan", Value: $spanValue})
+	}`
+
+	spans := make(map[string]string)
+	for _, rule := range rules {
+		if rule._kind != _ruleMaxSpan {
+			continue
+		}
+		key, err := resolveRuleKey(rule._keys[0], fields, structFieldMap, qfConstKeyMap)
+		if err != nil {
+			return "", fmt.Errorf("%s directive: %w", rule._kind, err)
+		}
+		if !hasKind(key._field, _qfKindRange) {
+			return "", fmt.Errorf("%s directive: %q is not a range filter", rule._kind, rule._keys[0])
+		}
+		spans[key._field._originalName] = rule._span
+	}
+
+	var rows []string
+	for _, field := range fields {
+		var gte, lte parserField
+		for _, pf := range structFieldMap[field._originalName] {
+			if pf.isRangeGte {
+				gte = pf
+			}
+			if pf.isRangeLte {
+				lte = pf
+			}
+		}
+		if gte._name == "" || lte._name == "" {
+			continue
+		}
+
+		lteValue, gteValue := "*res."+lte._name, "*res."+gte._name
+		less, ok := generateLessExpr(field, lteValue, gteValue)
+		if !ok {
+			if _, hasSpan := spans[field._originalName]; hasSpan {
+				return "", fmt.Errorf("field %s: maxspan is not supported for type %s", field._originalName, field._goType)
+			}
+			continue
+		}
+
+		conflict := namedReplace(conflictTmpl, map[string]string{
+			"$errField": fieldDisplayName(field),
+			"$gteKey":   qfConstKeyMap[gte],
+			"$lteKey":   qfConstKeyMap[lte],
+		})
+		var drops, notDefaulted string
+		for _, pf := range []parserField{gte, lte} {
+			if parserFieldDefault(field, pf) != nil {
+				drops += namedReplace(dropTmpl, map[string]string{"$key": qfConstKeyMap[pf], "$field": pf._name})
+				notDefaulted += fmt.Sprintf("!res._defaulted[%s] && ", qfConstKeyMap[pf])
+			}
+		}
+		if drops != "" {
+			conflict = "switch {\n" + drops + "default:\n\t" + conflict + "\n}"
+		}
+
+		var spanCheck string
+		if span, ok := spans[field._originalName]; ok {
+			spanValue, spanLimit, spanText, err := generateSpanExpr(field, lteValue, gteValue, span)
+			if err != nil {
+				return "", fmt.Errorf("field %s: %w", field._originalName, err)
+			}
+			spanCheck = namedReplace(spanTmpl, map[string]string{
+				"$notDefaulted": notDefaulted,
+				"$spanExceeded": spanValue + " > " + spanLimit,
+				"$spanValue":    spanText,
+				"$maxSpan":      span,
+				"$errField":     fieldDisplayName(field),
+				"$lteKey":       qfConstKeyMap[lte],
+			})
+		}
+
+		rows = append(rows, namedReplace(tmpl, map[string]string{
+			"$spanCheck": spanCheck,
+			"$less":      less,
+			"$conflict":  conflict,
+			"$gteField":  gte._name,
+			"$lteField":  lte._name,
+		}))
+	}
+	return strings.Join(rows, ""), nil
+}
+
+// generateLessExpr returns an expression reporting whether a is less than b,
+// false if values of the field have no order.
+func generateLessExpr(field _field, a, b string) (string, bool) {
+	goType := fieldBasicType(field)
+	switch {
+	case field._qf._decimal:
+		return generateDecimalLessExpr(field, a, b), true
+	case goType == "time.Time":
+		return fmt.Sprintf("%s.Before(%s)", strings.TrimPrefix(a, "*"), b), true
+	case isNumericClass(classifyGoType(goType)), goType == "string":
+		return fmt.Sprintf("%s < %s", a, b), true
+	case field._type != nil && field._type._comparer:
+		return fmt.Sprintf("%s.Compare(%s) < 0", strings.TrimPrefix(a, "*"), b), true
+	}
+	return "", false
+}
+
+// generateSpanExpr returns an expression computing the distance between hi and lo, the literal
+// of the maximum span and an expression formatting the distance in the unit of span.
+func generateSpanExpr(field _field, hi, lo, span string) (string, string, string, error) {
+	goType := fieldBasicType(field)
+	class := classifyGoType(goType)
+	switch {
+	case goType == "time.Time":
+		d, err := parseSpanDuration(span)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid maxspan %q: %w", span, err)
+		}
+		value := fmt.Sprintf("%s.Sub(%s)", strings.TrimPrefix(hi, "*"), lo)
+		suffix := strings.TrimLeft(span, "0123456789.")
+		suffix = suffix[strings.LastIndexAny(suffix, "0123456789.")+1:]
+		unit, err := parseSpanDuration("1" + suffix)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid maxspan %q: %w", span, err)
+		}
+		// rounded to a tenth of the unit, so end of day bounds do not show as 23:59:59.999999999
+		text := fmt.Sprintf("strconv.FormatFloat(math.Round(10*float64(%s)/%d)/10, 'f', -1, 64) + %q", value, unit, suffix)
+		return value, fmt.Sprintf("time.Duration(%d)", d), text, nil
+	case isNumericClass(class):
+		lit, err := numericLiteral(field, span)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid maxspan %q for type %s: %w", span, goType, err)
+		}
+		value := fmt.Sprintf("%s-%s", hi, lo)
+		return value, lit, fmt.Sprintf("fmt.Sprint(%s)", value), nil
+	}
+	return "", "", "", fmt.Errorf("maxspan is not supported for type %s", goType)
+}
+
+// parseSpanDuration parses time.ParseDuration values extended with days (d) and weeks (w).
+func parseSpanDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseUint(n, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseStructRules(t *testing.T) {
+	t.Parallel()
+
+	source := []string{
+		"package main",
+		"//ufi:exclusive a,b",
+		"",
+		"// Product filter.",
+		"//go:generate ufi --name=Product",
+		"//ufi:exclusive q,name",
+		"//ufi:together lat,lon",
+		"//ufi:maxspan created 90d",
+		"//ufi:version 3",
+		"type Product struct {",
+	}
+
+	// Act
+	got, err := parseStructRules(source, 5)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, []_structRule{
+		{_kind: _ruleExclusive, _keys: []string{"q", "name"}},
+		{_kind: _ruleTogether, _keys: []string{"lat", "lon"}},
+		{_kind: _ruleMaxSpan, _keys: []string{"created"}, _span: "90d"},
+		{_kind: _ruleVersion, _version: 3},
+	}, got)
+
+	// Act
+	got, err = parseStructRules(append(source, "// A doc comment mentioning //ufi:exclusive key,key[,...]"), 0)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+
+	// Act
+	_, err = parseStructRules([]string{"//ufi:version 0", "//go:generate ufi"}, 0)
+
+	// Assert
+	require.Error(t, err)
+
+	// Act
+	_, err = parseStructRules([]string{"//go:generate ufi", "//ufi:exclusive q"}, 0)
+
+	// Assert
+	require.Error(t, err)
+}
+
+func Test_generateRangeChecks(t *testing.T) {
+	t.Parallel()
+
+	lte := parserField{_name: "_CreatedLte", _kind: _qfKindRange, isRangeLte: true}
+	gte := parserField{_name: "_CreatedGte", _kind: _qfKindRange, isRangeGte: true}
+	fields := []_field{{
+		_originalName: "Created",
+		_goType:       "time.Time",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange}, _key: "created"},
+	}}
+
+	// Act
+	got, err := generateRangeChecks(
+		[]_structRule{{_kind: _ruleMaxSpan, _keys: []string{"created"}, _span: "1d"}},
+		fields,
+		map[string][]parserField{"Created": {lte, gte}},
+		map[parserField]string{lte: "_CreatedKey_lte", gte: "_CreatedKey_gte"},
+	)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, `
+if res._CreatedGte != nil && res._CreatedLte != nil {
+	if res._CreatedLte.Before(*res._CreatedGte) {
+		errs.add(&FilterError{Field: "Created", Key: _CreatedKey_gte, Constraint: FilterConstraintRange, Limit: q.Get(_CreatedKey_lte), Value: q.Get(_CreatedKey_gte)})
+	} else if res._CreatedLte.Sub(*res._CreatedGte) > time.Duration(86400000000000) {
+		errs.add(&FilterError{Field: "Created", Key: _CreatedKey_lte, Constraint: FilterConstraintMaxSpan, Limit: "1d", Value: strconv.FormatFloat(math.Round(10*float64(res._CreatedLte.Sub(*res._CreatedGte))/86400000000000)/10, 'f', -1, 64) + "d"})
+	}
+}
+`, got)
+
+	// Act
+	from := "now-30d"
+	fields[0]._qf._defaultFrom = &from
+	got, err = generateRangeChecks(
+		[]_structRule{{_kind: _ruleMaxSpan, _keys: []string{"created"}, _span: "1h30m"}},
+		fields,
+		map[string][]parserField{"Created": {lte, gte}},
+		map[parserField]string{lte: "_CreatedKey_lte", gte: "_CreatedKey_gte"},
+	)
+
+	// Assert
+	require.NoError(t, err)
+	require.Contains(t, got, "switch {\ncase res._defaulted[_CreatedKey_gte]:\n\tres._CreatedGte = nil\n\tdelete(res._defaulted, _CreatedKey_gte)\ndefault:")
+	require.Contains(t, got, "} else if !res._defaulted[_CreatedKey_gte] && res._CreatedLte.Sub(*res._CreatedGte) > time.Duration(5400000000000) {")
+	require.Contains(t, got, "/60000000000)/10, 'f', -1, 64) + \"m\"")
+}
+
+func Test_parseSpanDuration(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]time.Duration{
+		"90d":   90 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	} {
+		// Act
+		got, err := parseSpanDuration(input)
+
+		// Assert
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+}
+
+func TestRangeChecks(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\nimport \"time\"\n\ntype Product struct {\n" +
+		"\tAge     uint      `ufi:\"kind=range;key=age;default-to=18\"`\n" +
+		"\tCreated time.Time `ufi:\"kind=range;key=created;default-from=now-30d\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	clock := WithFilterClock(func() time.Time { return time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC) })
+	for _, query := range []string{
+		"age-from=10",
+		"age-from=30",
+		"age-from=30&age-to=20",
+		"created-to=2020-01-01",
+		"created-from=2025-01-01&created-to=2025-06-01",
+	} {
+		f, err := ParseFiltersQuery(query, clock)
+		if err != nil {
+			fmt.Printf("%q %v\n", query, err)
+			continue
+		}
+		fmt.Printf("%q age %v-%v %v, created %v %v, matches age 40: %v\n", query, f.GetAgeGte(), f.GetAgeLte(), f.IsAgeLteDefaulted(),
+			f.GetCreatedGte().Format(time.DateOnly), f.GetCreatedLte().Format(time.DateOnly), f.Match(&Product{Age: 40, Created: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main, _structRule{_kind: _ruleMaxSpan, _keys: []string{"created"}, _span: "90d"})
+
+	// Assert
+	require.Equal(t, `"age-from=10" age 10-18 true, created 2025-05-16 0001-01-01, matches age 40: false
+"age-from=30" age 30-0 false, created 2025-05-16 0001-01-01, matches age 40: true
+"age-from=30&age-to=20" filter "age-from" (field Age): lower bound "30" is greater than upper bound "20"
+"created-to=2020-01-01" age 0-18 true, created 0001-01-01 2020-01-01, matches age 40: false
+"created-from=2025-01-01&created-to=2025-06-01" filter "created-to" (field Created): range span 152d exceeds 90d
+`, got)
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// schemaVersion returns the version declared with the version directive, 1 by default.
+func schemaVersion(rules []_structRule) int {
+	version := 1
+	for _, rule := range rules {
+		if rule._kind == _ruleVersion {
+			version = rule._version
+		}
+	}
+	return version
+}
+
+// generateQueryKeysDef generates the set of query keys read by the filters, map keys aside, and the
+// keys of the extra constants, such as the fields parameter.
+func generateQueryKeysDef(qfConstKeyMap map[parserField]string, extraKeys ...string) string {
+	seen := make(map[string]struct{})
+	keys := make([]string, 0, len(qfConstKeyMap)+len(extraKeys))
+	for _, constName := range slices.Concat(slices.Collect(maps.Values(qfConstKeyMap)), extraKeys) {
+		if _, ok := seen[constName]; ok {
+			continue
+		}
+		seen[constName] = struct{}{}
+		keys = append(keys, constName+": true,")
+	}
+	sort.Strings(keys)
+	rows := []string{
+		"// _filterQueryKeys holds the query keys read by the filters, map keys are in _filterMapKeys.",
+		"var _filterQueryKeys = map[string]bool{",
+	}
+	rows = append(rows, keys...)
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n")
+}
+
+// generateSavedFilterFuncs generates FilterSchemaVersion, MarshalJSON and UnmarshalJSON.
+func generateSavedFilterFuncs(structRcv, structName string, rules []_structRule) string {
+	const tmpl = `
+// FilterSchemaVersion is the version of saved filters, set with the version directive. It should be
+// increased when keys or kinds change, see RegisterFilterMigration.
+const FilterSchemaVersion = $version
+
+// MarshalJSON saves the filters as the query they were parsed from, together with FilterSchemaVersion,
+// the time zone and the locale the query was read in. Defaults are left out, so UnmarshalJSON applies
+// the current ones, and relative times such as now-7d stay relative.
+func ($rcv $structName) MarshalJSON() ([]byte, error) {
+	return _filterSave($rcv._query, $rcv._options)
+}
+
+// UnmarshalJSON loads filters saved by MarshalJSON. They are migrated to FilterSchemaVersion and
+// parsed again with ParseFiltersValues, values that became invalid are reported as FilterErrors.
+func ($rcv *$structName) UnmarshalJSON(data []byte) error {
+	q, opts, err := _filterLoad(data)
+	if err != nil {
+		return err
+	}
+	res, err := ParseFiltersValues(q, opts...)
+	if err != nil {
+		return fmt.Errorf("cannot load saved filters: %w", err)
+	}
+	*$rcv = *res
+	return nil
+}
+`
+	return namedReplace(tmpl, map[string]string{
+		"$version":    fmt.Sprint(schemaVersion(rules)),
+		"$rcv":        structRcv,
+		"$structName": structName,
+	})
+}
+
+const filterSavedFuncs = `
+type _filterSaved struct {
+	Version  int                           ` + "`json:\"version\"`" + `
+	Filters  map[string]_filterSavedValues ` + "`json:\"filters\"`" + `
+	Location string                        ` + "`json:\"location,omitempty\"`" + `
+	// Offset is the UTC offset in seconds of locations without an IANA name, such as time.FixedZone.
+	Offset   *int                          ` + "`json:\"offset,omitempty\"`" + `
+	Locale   *_filterSavedLocale           ` + "`json:\"locale,omitempty\"`" + `
+	GoSyntax bool                          ` + "`json:\"goSyntax,omitempty\"`" + `
+}
+
+// _filterSavedValues are the values of a saved key, a string when the key was sent once.
+type _filterSavedValues []string
+
+func (v _filterSavedValues) MarshalJSON() ([]byte, error) {
+	if len(v) == 1 {
+		return json.Marshal(v[0])
+	}
+	return json.Marshal([]string(v))
+}
+
+func (v *_filterSavedValues) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*v = _filterSavedValues{value}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(v))
+}
+
+type _filterSavedLocale struct {
+	Decimal string ` + "`json:\"decimal\"`" + `
+	Group   string ` + "`json:\"group\"`" + `
+}
+
+var _filterMigrations sync.Map
+
+// RegisterFilterMigration registers migrate to upgrade the query of filters saved with version to
+// version+1, e.g. to rename a key. Saved filters are migrated one version at a time up to
+// FilterSchemaVersion, versions without a migration are kept as they are. Migrations are usually
+// registered in init.
+func RegisterFilterMigration(version int, migrate func(q url.Values) error) {
+	_filterMigrations.Store(version, migrate)
+}
+
+// _filterSavedQuery returns the values of q read by the filters.
+func _filterSavedQuery(q url.Values) url.Values {
+	saved := make(url.Values)
+	for key, values := range q {
+		name, attr, isAttr := strings.Cut(key, "[")
+		if _filterQueryKeys[key] || isAttr && _filterMapKeys[name] && strings.HasSuffix(attr, "]") {
+			saved[key] = values
+		}
+	}
+	return saved
+}
+
+func _filterSave(q url.Values, o *filterOptions) ([]byte, error) {
+	saved := _filterSaved{Version: FilterSchemaVersion, Filters: make(map[string]_filterSavedValues, len(q))}
+	for key, values := range q {
+		saved.Filters[key] = values
+	}
+	if o != nil {
+		saved.Location = o.location.String()
+		if _, err := time.LoadLocation(saved.Location); err != nil {
+			_, offset := o.now.In(o.location).Zone()
+			saved.Offset = &offset
+		}
+		if loc := o.numberLocale(nil); loc != nil {
+			saved.Locale = &_filterSavedLocale{Decimal: string(loc.Decimal), Group: loc.Group}
+		}
+		saved.GoSyntax = o.goSyntax
+	}
+	return json.Marshal(saved)
+}
+
+// _filterLoad reads filters saved by _filterSave, migrates their query and returns it with the options to parse it.
+func _filterLoad(data []byte) (url.Values, []FilterOption, error) {
+	var saved _filterSaved
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse saved filters: %w", err)
+	}
+	if saved.Version < 1 || saved.Version > FilterSchemaVersion {
+		return nil, nil, fmt.Errorf("saved filters have version %d, expected 1 to %d", saved.Version, FilterSchemaVersion)
+	}
+	q := make(url.Values, len(saved.Filters))
+	for key, values := range saved.Filters {
+		q[key] = values
+	}
+	for v := saved.Version; v < FilterSchemaVersion; v++ {
+		if migrate, ok := _filterMigrations.Load(v); ok {
+			if err := migrate.(func(url.Values) error)(q); err != nil {
+				return nil, nil, fmt.Errorf("cannot migrate saved filters from version %d: %w", v, err)
+			}
+		}
+	}
+
+	var opts []FilterOption
+	if saved.Offset != nil {
+		opts = append(opts, WithFilterLocation(time.FixedZone(saved.Location, *saved.Offset)))
+	} else if saved.Location != "" {
+		loc, err := time.LoadLocation(saved.Location)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot load time zone of saved filters: %w", err)
+		}
+		opts = append(opts, WithFilterLocation(loc))
+	}
+	if l := saved.Locale; l != nil {
+		decimal, _ := utf8.DecodeRuneInString(l.Decimal)
+		opts = append(opts, WithFilterLocale(FilterLocale{Decimal: decimal, Group: l.Group}))
+	}
+	if saved.GoSyntax {
+		opts = append(opts, func(o *filterOptions) {
+			o.goSyntax = true
+		})
+	}
+	return q, opts, nil
+}
+`
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_schemaVersion(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 1, schemaVersion(nil))
+	require.Equal(t, 3, schemaVersion([]_structRule{{_kind: _ruleMaxSpan}, {_kind: _ruleVersion, _version: 3}}))
+}
+
+func Test_generateQueryKeysDef(t *testing.T) {
+	t.Parallel()
+
+	exact := parserField{_name: "_SKUExact", _kind: _qfKindExact}
+	multi := parserField{_name: "_SKUMultiValue", _kind: _qfKindMultiValue}
+	lte := parserField{_name: "_SKULte", _kind: _qfKindRange, isRangeLte: true}
+
+	// Act
+	got := generateQueryKeysDef(map[parserField]string{exact: "_SKUKey", multi: "_SKUKey", lte: "_SKUKey_lte"})
+
+	// Assert
+	require.Equal(t, `// _filterQueryKeys holds the query keys read by the filters, map keys are in _filterMapKeys.
+var _filterQueryKeys = map[string]bool{
+_SKUKey: true,
+_SKUKey_lte: true,
+}`, got)
+
+	// Act
+	got = generateQueryKeysDef(map[parserField]string{exact: "_SKUKey"}, "_filterFieldsKey")
+
+	// Assert
+	require.Contains(t, got, "_SKUKey: true,\n_filterFieldsKey: true,")
+}
+
+func Test_generateSavedFilterFuncs(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	got := generateSavedFilterFuncs("_Pr", "_ProductFilter", []_structRule{{_kind: _ruleVersion, _version: 2}})
+
+	// Assert
+	require.Contains(t, got, "const FilterSchemaVersion = 2")
+	require.Contains(t, got, "func (_Pr _ProductFilter) MarshalJSON() ([]byte, error) {\n\treturn _filterSave(_Pr._query, _Pr._options)")
+	require.Contains(t, got, "func (_Pr *_ProductFilter) UnmarshalJSON(data []byte) error {")
+}
+
+func TestFilterSavedRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\nimport \"time\"\n\ntype Product struct {\n" +
+		"\tName    string    `ufi:\"kind=exact;key=name\"`\n" +
+		"\tCreated time.Time `ufi:\"kind=range;key=created\"`\n}\n"
+	const main = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+func main() {
+	q := url.Values{"name": {"a", "b"}, "created-from": {"2025-01-01T10:00:00"}}
+	f, err := ParseFiltersValues(q, WithFilterLocation(time.FixedZone("UTC+3", 3*3600)))
+	if err != nil {
+		panic(err)
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+	byValue, err := json.Marshal(*f)
+	if err != nil {
+		panic(err)
+	}
+	type search struct {
+		Filters _ProductFilter ` + "`" + `json:"filters"` + "`" + `
+		Page    int            ` + "`" + `json:"page"` + "`" + `
+	}
+	embedded, err := json.Marshal(search{Filters: *f, Page: 2})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(byValue) == string(data), string(embedded) == ` + "`" + `{"filters":` + "`" + `+string(data)+` + "`" + `,"page":2}` + "`" + `)
+	var decoded search
+	if err := json.Unmarshal(embedded, &decoded); err != nil {
+		panic(err)
+	}
+	fmt.Println(decoded.Filters.GetCreatedGte().Format(time.RFC3339), decoded.Filters._query["name"], decoded.Page)
+	var loaded _ProductFilter
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		panic(err)
+	}
+	fmt.Println(loaded.GetCreatedGte().Format(time.RFC3339), loaded._query["name"])
+
+	if err := json.Unmarshal([]byte(` + "`" + `{"version":1,"filters":{"name":"c"},"location":"Europe/Berlin"}` + "`" + `), &loaded); err != nil {
+		panic(err)
+	}
+	fmt.Println(loaded.GetNameExact(), loaded._options.location)
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `{"version":1,"filters":{"created-from":"2025-01-01T10:00:00","name":["a","b"]},"location":"UTC+3","offset":10800}
+true true
+2025-01-01T10:00:00+03:00 [a b] 2
+2025-01-01T10:00:00+03:00 [a b]
+c Europe/Berlin
+`, got)
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// validateSetKinds checks that any, all and none kinds, and only them, are used on slice fields.
+func validateSetKinds(field _field) error {
+	for _, kind := range field._qf._kindList {
+		switch {
+		case field._slice && !isSetKind(kind):
+			return fmt.Errorf("%s kind is not supported for slice fields, use any, all or none", kind)
+		case !field._slice && isSetKind(kind):
+			return fmt.Errorf("%s kind requires a slice field", kind)
+		}
+	}
+	if field._slice && field._qf._default != nil {
+		return fmt.Errorf("default option is not supported for any, all and none kinds")
+	}
+	return nil
+}
+
+// fieldValueType returns the type of the struct field, which differs from _goType for slice fields.
+func fieldValueType(field _field) string {
+	switch {
+	case field._slice:
+		return "[]" + field._goType
+	case field._map:
+		return "map[string]" + field._goType
+	case field._lon != nil:
+		return "FilterGeoPoint"
+	}
+	return field._goType
+}
+
+// generateSetMismatchExpr returns an expression reporting whether the set x of the field
+// fails the set filter of kind holding values.
+func generateSetMismatchExpr(field _field, kind queryFilterKind, values string) string {
+	equal := fmt.Sprintf("func(a, b %s) bool { return %s }", field._goType, generateEqualExpr(field, "a", "b"))
+	switch kind {
+	case _qfKindAll:
+		return fmt.Sprintf("!_filterHasAll(x, %s, %s)", values, equal)
+	case _qfKindNone:
+		return fmt.Sprintf("_filterHasAny(x, %s, %s)", values, equal)
+	}
+	return fmt.Sprintf("!_filterHasAny(x, %s, %s)", values, equal)
+}
+
+const filterSetMatchFuncs = `
+// _filterHasAny reports whether set contains any of values.
+func _filterHasAny[T any](set, values []T, equal func(a, b T) bool) bool {
+	for _, v := range values {
+		if slices.ContainsFunc(set, func(e T) bool { return equal(e, v) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// _filterHasAll reports whether set contains all of values.
+func _filterHasAll[T any](set, values []T, equal func(a, b T) bool) bool {
+	for _, v := range values {
+		if !slices.ContainsFunc(set, func(e T) bool { return equal(e, v) }) {
+			return false
+		}
+	}
+	return true
+}`
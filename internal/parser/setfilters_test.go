@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateSetKinds(t *testing.T) {
+	t.Parallel()
+
+	kinds := func(kinds ...queryFilterKind) utiQueryFilter {
+		return utiQueryFilter{_kindList: kinds}
+	}
+	require.NoError(t, validateSetKinds(_field{_goType: "string", _slice: true, _qf: kinds(_qfKindAny, _qfKindAll, _qfKindNone)}))
+	require.NoError(t, validateSetKinds(_field{_goType: "string", _qf: kinds(_qfKindExact)}))
+	require.Error(t, validateSetKinds(_field{_goType: "string", _slice: true, _qf: kinds(_qfKindExact)}))
+	require.Error(t, validateSetKinds(_field{_goType: "string", _qf: kinds(_qfKindAny)}))
+
+	withDefault := kinds(_qfKindAny)
+	withDefault._default = ptr("a")
+	require.Error(t, validateSetKinds(_field{_goType: "string", _slice: true, _qf: withDefault}))
+}
+
+func Test_generateSetMismatchExpr(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_goType: "string", _slice: true}
+	require.Equal(t, "!_filterHasAny(x, *f.a, func(a, b string) bool { return a == b })", generateSetMismatchExpr(field, _qfKindAny, "*f.a"))
+	require.Equal(t, "!_filterHasAll(x, *f.a, func(a, b string) bool { return a == b })", generateSetMismatchExpr(field, _qfKindAll, "*f.a"))
+	require.Equal(t, "_filterHasAny(x, *f.a, func(a, b string) bool { return a == b })", generateSetMismatchExpr(field, _qfKindNone, "*f.a"))
+}
+
+func Test_queryKey_setKinds(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_qf: utiQueryFilter{_key: "tags"}}
+	require.Equal(t, "tags-all", queryKey(field, parserField{_kind: _qfKindAll}))
+	require.Equal(t, "All", setKindPostfix(_qfKindAll))
+}
+
+func TestSetFilters(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tTags        []string `ufi:\"kind=any,all,none;key=tags\"`\n" +
+		"\tCategoryIDs []uint64 `ufi:\"kind=any;key=categories\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	items := []Product{
+		{Tags: []string{"red", "big"}, CategoryIDs: []uint64{1, 2}},
+		{Tags: []string{"red"}, CategoryIDs: []uint64{3}},
+		{Tags: []string{"blue", "small"}},
+		{},
+	}
+	for _, query := range []string{
+		"tags-any=red,blue",
+		"tags-all=red,big",
+		"tags-none=red",
+		"tags-any=red&tags-none=big",
+		"categories-any=2,3",
+		"tags-all=red&categories-any=3",
+		"categories-any=x",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		var matches []int
+		for i := range items {
+			if f.Match(&items[i]) {
+				matches = append(matches, i)
+			}
+		}
+		fmt.Printf("%s %v\n", query, matches)
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `tags-any=red,blue [0 1 2]
+tags-all=red,big [0]
+tags-none=red [2 3]
+tags-any=red&tags-none=big [1]
+categories-any=2,3 [0 1]
+tags-all=red&categories-any=3 [1]
+filter "categories-any" (field CategoryIDs): invalid value "x": strconv.ParseUint: parsing "x": invalid syntax
+`, got)
+}
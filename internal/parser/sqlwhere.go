@@ -0,0 +1,354 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// generateWhereFunc generates Where, rendering the filters that are set as an SQL condition.
+func generateWhereFunc(structRcv, structName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// Where renders the filters that are set as an SQL condition, joined with AND, and its arguments.
+// Placeholders are rendered by d, the condition is empty when no filter is set.
+func ($rcv *$structName) Where(d FilterDialect) (string, []any) {
+	w := &_filterWhere{dialect: d}
+	$conds
+	return w.String(), w.args
+}
+`
+	var conds []string
+	for _, field := range fields {
+		conds = append(conds, generateWhereConds(structRcv, field, structFieldMap[field._originalName])...)
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$rcv":        structRcv,
+		"$structName": structName,
+		"$conds":      strings.Join(conds, "\n"),
+	})
+}
+
+// generateWhereConds generates the statements adding the conditions of the filters of the field to w.
+func generateWhereConds(structRcv string, field _field, pfs []parserField) []string {
+	var conds []string
+	column := strconv.Quote(fieldColumn(field))
+	var exact string
+	for _, pf := range pfs {
+		if pf._kind == _qfKindExact {
+			exact = structRcv + "." + pf._name
+		}
+	}
+	for _, pf := range pfs {
+		filter := structRcv + "." + pf._name
+		cmp := func(op string) string {
+			return fmt.Sprintf("if %s != nil {\n\tw.cmp(%s, %q, *%s)\n}", filter, column, op, filter)
+		}
+		switch {
+		case pf.isRangeGte:
+			conds = append(conds, cmp(">="))
+		case pf.isRangeLte:
+			conds = append(conds, cmp("<="))
+		case pf._kind == _qfKindExact:
+			conds = append(conds, cmp("="))
+		case isSetKind(pf._kind):
+			conds = append(conds, fmt.Sprintf("if %s != nil {\n\t_filterWhereSet(w, %s, FilterSet%s, *%s)\n}",
+				filter, column, setKindPostfix(pf._kind), filter))
+		case pf._kind == _qfKindMap:
+			conds = append(conds, fmt.Sprintf("if %s != nil {\n\t_filterWhereAttrs(w, %s, *%s, %s, %s)\n}",
+				filter, column, filter, mapAttrsVarName(field), generateMapJSONType(field)))
+		case pf._kind == _qfKindGeo:
+			if where := generateGeoWhere(structRcv, field, pf, filter); where != "" {
+				conds = append(conds, where)
+			}
+		case pf._kind == _qfKindInCIDR:
+			conds = append(conds, fmt.Sprintf("if %s != nil {\n\tw.conds = append(w.conds, d.PrefixCondition(%s, *%s, w.arg))\n}",
+				filter, column, filter))
+		case pf._kind == _qfKindText:
+			conds = append(conds, fmt.Sprintf("if %s != nil {\n\tw.conds = append(w.conds, d.TextCondition(%s, *%s, w.arg))\n}",
+				filter, column, filter))
+		case pf._kind == _qfKindMultiValue:
+			// a single value of a key shared with the exact kind is already compared with =
+			cond := filter + " != nil"
+			if exact != "" {
+				cond += " && " + exact + " == nil"
+			}
+			conds = append(conds, fmt.Sprintf("if %s {\n\t_filterWhereIn(w, %s, *%s)\n}", cond, column, filter))
+		}
+	}
+	return conds
+}
+
+const filterWhereDef = `
+// FilterSetKind is how the values of a slice field are compared with the values of a filter.
+type FilterSetKind string
+
+const (
+	// FilterSetAny matches slices containing any of the filter values.
+	FilterSetAny FilterSetKind = "any"
+	// FilterSetAll matches slices containing all of the filter values.
+	FilterSetAll FilterSetKind = "all"
+	// FilterSetNone matches slices containing none of the filter values.
+	FilterSetNone FilterSetKind = "none"
+)
+
+// FilterDialect renders the parts of SQL conditions that differ between databases.
+type FilterDialect interface {
+	// Placeholder returns the placeholder of the n-th argument, counting from 1.
+	Placeholder(n int) string
+	// SetCondition renders a condition of kind on the set stored in column. values is the slice
+	// of filter values, arg adds an argument and returns its placeholder.
+	SetCondition(column string, kind FilterSetKind, values any, arg func(any) string) string
+	// JSONField renders the value of the name key of the JSON object stored in column, as typ.
+	// The name is passed with arg rather than rendered, it comes from the query.
+	JSONField(column, name string, typ FilterJSONType, arg func(any) string) string
+	// TextCondition renders a condition matching the text stored in column that holds every term.
+	TextCondition(column string, terms []string, arg func(any) string) string
+	// PrefixCondition renders a condition matching the address stored in column that is in any of prefixes.
+	PrefixCondition(column string, prefixes []netip.Prefix, arg func(any) string) string
+	// TimeBucket renders the start of the period of unit holding the time stored in column. The
+	// unit is hour, day, week, month or year, weeks start on Monday.
+	TimeBucket(column, unit string) string
+}
+
+// FilterJSONType is the type a value read from a JSON column is compared as.
+type FilterJSONType string
+
+const (
+	// FilterJSONText compares values as strings.
+	FilterJSONText FilterJSONType = "text"
+	// FilterJSONNumber compares values as numbers.
+	FilterJSONNumber FilterJSONType = "number"
+	// FilterJSONBool compares values as booleans.
+	FilterJSONBool FilterJSONType = "bool"
+)
+
+// _filterJSONPath returns the JSON path of the name key, for dialects taking paths.
+func _filterJSONPath(name string) string {
+	return "$." + strconv.Quote(name)
+}
+
+type _filterDialectPostgres struct{}
+
+func (_filterDialectPostgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (_filterDialectPostgres) SetCondition(column string, kind FilterSetKind, values any, arg func(any) string) string {
+	switch kind {
+	case FilterSetAll:
+		return column + " @> " + arg(values)
+	case FilterSetNone:
+		return "NOT (" + column + " && " + arg(values) + ")"
+	}
+	return column + " && " + arg(values)
+}
+
+func (_filterDialectPostgres) JSONField(column, name string, typ FilterJSONType, arg func(any) string) string {
+	field := "(" + column + "->>" + arg(name) + ")"
+	switch typ {
+	case FilterJSONNumber:
+		return field + "::numeric"
+	case FilterJSONBool:
+		return field + "::boolean"
+	}
+	return field
+}
+
+func (_filterDialectPostgres) TextCondition(column string, terms []string, arg func(any) string) string {
+	return "to_tsvector('simple', " + column + ") @@ to_tsquery('simple', " + arg(strings.Join(terms, " & ")) + ")"
+}
+
+func (_filterDialectPostgres) PrefixCondition(column string, prefixes []netip.Prefix, arg func(any) string) string {
+	conds := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		conds = append(conds, column+" <<= "+arg(p.String())+"::inet")
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+func (_filterDialectPostgres) TimeBucket(column, unit string) string {
+	return "date_trunc('" + unit + "', " + column + ")"
+}
+
+type _filterDialectMySQL struct{}
+
+func (_filterDialectMySQL) Placeholder(int) string {
+	return "?"
+}
+
+func (_filterDialectMySQL) SetCondition(column string, kind FilterSetKind, values any, arg func(any) string) string {
+	var conds []string
+	for _, v := range _filterSetElems(values) {
+		conds = append(conds, arg(v)+" MEMBER OF("+column+")")
+	}
+	switch kind {
+	case FilterSetAll:
+		return "(" + strings.Join(conds, " AND ") + ")"
+	case FilterSetNone:
+		return "NOT (" + strings.Join(conds, " OR ") + ")"
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+func (_filterDialectMySQL) JSONField(column, name string, typ FilterJSONType, arg func(any) string) string {
+	field := "JSON_EXTRACT(" + column + ", " + arg(_filterJSONPath(name)) + ")"
+	if typ == FilterJSONText {
+		return "JSON_UNQUOTE(" + field + ")"
+	}
+	return field
+}
+
+func (_filterDialectMySQL) TextCondition(column string, terms []string, arg func(any) string) string {
+	return "MATCH(" + column + ") AGAINST(" + arg("+"+strings.Join(terms, " +")) + " IN BOOLEAN MODE)"
+}
+
+func (_filterDialectMySQL) PrefixCondition(column string, prefixes []netip.Prefix, arg func(any) string) string {
+	return _filterPrefixBetween(column, prefixes, arg)
+}
+
+func (_filterDialectMySQL) TimeBucket(column, unit string) string {
+	switch unit {
+	case "hour":
+		return "DATE_FORMAT(" + column + ", '%Y-%m-%d %H:00:00')"
+	case "week":
+		return "DATE_SUB(DATE(" + column + "), INTERVAL WEEKDAY(" + column + ") DAY)"
+	case "month":
+		return "DATE_FORMAT(" + column + ", '%Y-%m-01')"
+	case "year":
+		return "DATE_FORMAT(" + column + ", '%Y-01-01')"
+	}
+	return "DATE(" + column + ")"
+}
+
+type _filterDialectSQLite struct{}
+
+func (_filterDialectSQLite) Placeholder(int) string {
+	return "?"
+}
+
+func (_filterDialectSQLite) SetCondition(column string, kind FilterSetKind, values any, arg func(any) string) string {
+	exists := func(cond string) string {
+		return "EXISTS (SELECT 1 FROM json_each(" + column + ") WHERE value " + cond + ")"
+	}
+	elems := _filterSetElems(values)
+	if kind == FilterSetAll {
+		conds := make([]string, 0, len(elems))
+		for _, v := range elems {
+			conds = append(conds, exists("= "+arg(v)))
+		}
+		return "(" + strings.Join(conds, " AND ") + ")"
+	}
+	placeholders := make([]string, 0, len(elems))
+	for _, v := range elems {
+		placeholders = append(placeholders, arg(v))
+	}
+	cond := exists("IN (" + strings.Join(placeholders, ", ") + ")")
+	if kind == FilterSetNone {
+		return "NOT " + cond
+	}
+	return cond
+}
+
+func (_filterDialectSQLite) JSONField(column, name string, _ FilterJSONType, arg func(any) string) string {
+	return "json_extract(" + column + ", " + arg(_filterJSONPath(name)) + ")"
+}
+
+func (_filterDialectSQLite) TextCondition(column string, terms []string, arg func(any) string) string {
+	conds := make([]string, 0, len(terms))
+	for _, term := range terms {
+		conds = append(conds, "LOWER("+column+") LIKE "+arg("%"+term+"%"))
+	}
+	return "(" + strings.Join(conds, " AND ") + ")"
+}
+
+func (_filterDialectSQLite) PrefixCondition(column string, prefixes []netip.Prefix, arg func(any) string) string {
+	return _filterPrefixBetween(column, prefixes, arg)
+}
+
+func (_filterDialectSQLite) TimeBucket(column, unit string) string {
+	switch unit {
+	case "hour":
+		return "strftime('%Y-%m-%d %H:00:00', " + column + ")"
+	case "week":
+		return "date(" + column + ", 'weekday 0', '-6 days')"
+	case "month":
+		return "date(" + column + ", 'start of month')"
+	case "year":
+		return "date(" + column + ", 'start of year')"
+	}
+	return "date(" + column + ")"
+}
+
+// _filterPrefixBetween compares binary addresses, 4 bytes for IPv4 and 16 for IPv6 as INET6_ATON
+// stores them, with the first and last address of each prefix.
+func _filterPrefixBetween(column string, prefixes []netip.Prefix, arg func(any) string) string {
+	conds := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		first, last := p.Masked().Addr().AsSlice(), p.Masked().Addr().AsSlice()
+		for bit := p.Bits(); bit < len(last)*8; bit++ {
+			last[bit/8] |= 1 << (7 - bit%8)
+		}
+		conds = append(conds, column+" BETWEEN "+arg(first)+" AND "+arg(last))
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+var (
+	// FilterDialectPostgres numbers placeholders: $1, $2, ... Slice fields are array columns compared
+	// with && and @>, the filter values are passed as a single slice argument, which drivers
+	// such as pgx encode as an array. Map fields are jsonb columns read with ->>, text filters use
+	// to_tsvector with the simple configuration, in-cidr filters compare inet columns with <<=.
+	FilterDialectPostgres FilterDialect = _filterDialectPostgres{}
+	// FilterDialectMySQL renders every placeholder as a question mark. Slice fields are JSON array
+	// columns looked up with MEMBER OF, map fields are JSON object columns read with JSON_EXTRACT.
+	// Text filters use MATCH AGAINST in boolean mode and need a FULLTEXT index. In-cidr filters
+	// compare binary addresses as stored by INET6_ATON.
+	FilterDialectMySQL FilterDialect = _filterDialectMySQL{}
+	// FilterDialectSQLite renders every placeholder as a question mark. Slice fields are JSON array
+	// columns looked up with EXISTS subqueries over json_each, map fields are read with json_extract.
+	// Text filters are approximated with LIKE per term, which also matches terms inside words.
+	// In-cidr filters compare binary addresses, 4 bytes for IPv4 and 16 for IPv6.
+	FilterDialectSQLite FilterDialect = _filterDialectSQLite{}
+)
+
+type _filterWhere struct {
+	dialect FilterDialect
+	conds   []string
+	args    []any
+}
+
+func (w *_filterWhere) arg(v any) string {
+	w.args = append(w.args, v)
+	return w.dialect.Placeholder(len(w.args))
+}
+
+func (w *_filterWhere) cmp(column, op string, v any) {
+	w.conds = append(w.conds, column+" "+op+" "+w.arg(v))
+}
+
+func _filterWhereIn[T any](w *_filterWhere, column string, values []T) {
+	placeholders := make([]string, 0, len(values))
+	for _, v := range values {
+		placeholders = append(placeholders, w.arg(v))
+	}
+	w.conds = append(w.conds, column+" IN ("+strings.Join(placeholders, ", ")+")")
+}
+
+func _filterWhereSet[T any](w *_filterWhere, column string, kind FilterSetKind, values []T) {
+	w.conds = append(w.conds, w.dialect.SetCondition(column, kind, values, w.arg))
+}
+
+// _filterSetElems returns the elements of the slice values, for dialects passing them one by one.
+func _filterSetElems(values any) []any {
+	rv := reflect.ValueOf(values)
+	elems := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		elems = append(elems, rv.Index(i).Interface())
+	}
+	return elems
+}
+
+func (w *_filterWhere) String() string {
+	return strings.Join(w.conds, " AND ")
+}
+`
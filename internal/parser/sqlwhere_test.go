@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateWhereFunc(t *testing.T) {
+	t.Parallel()
+
+	field := _field{
+		_originalName: "SKU",
+		_goType:       "uint64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindExact, _qfKindMultiValue}, _column: "p.sku"},
+	}
+	pfs := []parserField{{_name: "_SKUExact", _kind: _qfKindExact}, {_name: "_SKUMultiValue", _kind: _qfKindMultiValue}}
+
+	// Act
+	got := generateWhereFunc("f", "_ProductFilter", []_field{field}, map[string][]parserField{"SKU": pfs})
+
+	// Assert
+	require.Contains(t, got, "if f._SKUExact != nil {\n\tw.cmp(\"p.sku\", \"=\", *f._SKUExact)\n}")
+	require.Contains(t, got, "if f._SKUMultiValue != nil && f._SKUExact == nil {\n\t_filterWhereIn(w, \"p.sku\", *f._SKUMultiValue)\n}")
+}
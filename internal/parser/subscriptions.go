@@ -0,0 +1,407 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+func subscriptionIndexName(origStructName string) string {
+	return origStructName + "SubscriptionIndex"
+}
+
+// subscriptionIndexing reports how the conditions of the field are indexed: exact and multi-value
+// conditions of built-in types in hash buckets, ranges and exact values of other ordered types
+// in interval trees. Other conditions are checked with Match.
+func subscriptionIndexing(field _field) (buckets, intervals bool) {
+	if field._slice || field._map || field._lon != nil || field._qf._decimal {
+		return false, false
+	}
+	basic := fieldBasicType(field)
+	class := classifyGoType(basic)
+	hashable := isNumericClass(class) || class == _goTypeClassString || basic == "bool"
+	ordered := isNumericClass(class) || class == _goTypeClassString || basic == "time.Time" || field._type != nil && field._type._comparer
+	values := hasKind(field, _qfKindExact) || hasKind(field, _qfKindMultiValue)
+	return hashable && values, ordered && (hasKind(field, _qfKindRange) || !hashable && values)
+}
+
+// generateCompareFunc returns a function ordering values of the field like cmp.Compare.
+func generateCompareFunc(field _field) string {
+	class := classifyGoType(fieldBasicType(field))
+	if isNumericClass(class) || class == _goTypeClassString {
+		return fmt.Sprintf("cmp.Compare[%s]", field._goType)
+	}
+	return fmt.Sprintf("func(a, b %s) int { return a.Compare(b) }", field._goType)
+}
+
+// generateSubscriptionIndex generates a reverse index of filters, finding the filters an item matches.
+func generateSubscriptionIndex(structName, origStructName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// $subIndex holds saved filters, e.g. of alerts, under IDs of type K and finds the filters a
+// $origStruct matches without evaluating each of them: exact and multi-value conditions are kept
+// in hash buckets, range conditions in interval trees, and a filter matches when the item hits
+// all of its conditions. Filters with other conditions are checked with Match after that, filters
+// without indexed conditions are always checked with Match. It is safe for concurrent use.
+type $subIndex[K comparable] struct {
+	mu    sync.RWMutex
+	slots map[K]int
+	subs  []*_filterSub[K, *$structName]
+	free  []int
+	// scan holds the slots of filters without indexed conditions.
+	scan  map[int]struct{}
+	dirty bool
+	$indexFields
+}
+
+// New$subIndex returns an empty index.
+func New$subIndex[K comparable]() *$subIndex[K] {
+	return &$subIndex[K]{
+		slots: make(map[K]int),
+		scan:  make(map[int]struct{}),
+		$initFields
+	}
+}
+
+// Add stores f under id, replacing the filter stored under it before.
+func (ix *$subIndex[K]) Add(id K, f *$structName) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	var slot int
+	if n := len(ix.free); n > 0 {
+		slot, ix.free = ix.free[n-1], ix.free[:n-1]
+	} else {
+		slot = len(ix.subs)
+		ix.subs = append(ix.subs, nil)
+	}
+	conds, residual := ix.index(f, slot, true)
+	ix.subs[slot] = &_filterSub[K, *$structName]{id: id, filter: f, conds: conds, residual: residual}
+	ix.slots[id] = slot
+	if conds == 0 {
+		ix.scan[slot] = struct{}{}
+	}
+	ix.dirty = true
+}
+
+// Remove removes the filter stored under id and reports whether there was one.
+func (ix *$subIndex[K]) Remove(id K) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.remove(id)
+}
+
+func (ix *$subIndex[K]) remove(id K) bool {
+	slot, ok := ix.slots[id]
+	if !ok {
+		return false
+	}
+	ix.index(ix.subs[slot].filter, slot, false)
+	ix.subs[slot] = nil
+	ix.free = append(ix.free, slot)
+	delete(ix.slots, id)
+	delete(ix.scan, slot)
+	ix.dirty = true
+	return true
+}
+
+// Len returns the number of stored filters.
+func (ix *$subIndex[K]) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.slots)
+}
+
+// Match returns the IDs of the filters v matches, in no particular order.
+func (ix *$subIndex[K]) Match(v *$origStruct) []K {
+	ix.rlock()
+	defer ix.mu.RUnlock()
+
+	hits := make(map[int]int)
+	$probes
+	var res []K
+	for slot, n := range hits {
+		sub := ix.subs[slot]
+		if n == sub.conds && (!sub.residual || sub.filter.Match(v)) {
+			res = append(res, sub.id)
+		}
+	}
+	for slot := range ix.scan {
+		if sub := ix.subs[slot]; sub.filter.Match(v) {
+			res = append(res, sub.id)
+		}
+	}
+	return res
+}
+
+// rlock read locks ix once its interval trees hold every change. They are rebuilt once after
+// changes rather than on every Add, changes made while waiting for the write lock are taken in too.
+func (ix *$subIndex[K]) rlock() {
+	for {
+		ix.mu.RLock()
+		if !ix.dirty {
+			return
+		}
+		ix.mu.RUnlock()
+		ix.mu.Lock()
+		ix.build()
+		ix.mu.Unlock()
+	}
+}
+
+func (ix *$subIndex[K]) build() {
+	if !ix.dirty {
+		return
+	}
+	$builds
+	ix.dirty = false
+}
+
+// index adds the conditions of f to the index or, when add is false, removes them. It returns the number
+// of indexed conditions and whether f has others.
+func (ix *$subIndex[K]) index(f *$structName, slot int, add bool) (int, bool) {
+	conds := 0
+	$indexConds
+	residual := $residual
+	return conds, residual
+}
+`
+	var indexFields, initFields, probes, builds, indexConds, residual []string
+	for _, field := range fields {
+		pfs := structFieldMap[field._originalName]
+		if len(pfs) == 0 {
+			continue
+		}
+		buckets, intervals := subscriptionIndexing(field)
+		bucketsName, intervalsName := "_"+field._originalName+"Buckets", "_"+field._originalName+"Intervals"
+		var fieldProbes []string
+		if buckets {
+			indexFields = append(indexFields, fmt.Sprintf("%s _filterBuckets[%s]", bucketsName, field._goType))
+			initFields = append(initFields, fmt.Sprintf("%s: make(_filterBuckets[%s]),", bucketsName, field._goType))
+			fieldProbes = append(fieldProbes, fmt.Sprintf("ix.%s.probe(x, hits)", bucketsName))
+		}
+		if intervals {
+			indexFields = append(indexFields, fmt.Sprintf("%s *_filterIntervals[%s]", intervalsName, field._goType))
+			initFields = append(initFields, fmt.Sprintf("%s: &_filterIntervals[%s]{cmp: %s},", intervalsName, field._goType, generateCompareFunc(field)))
+			fieldProbes = append(fieldProbes, fmt.Sprintf("ix.%s.probe(x, hits)", intervalsName))
+			builds = append(builds, fmt.Sprintf("ix.%s.build()", intervalsName))
+		}
+		if len(fieldProbes) > 0 {
+			probes = append(probes, fmt.Sprintf("if x, ok := %s(v); ok {\n\t%s\n}", fieldValueFuncName(field), strings.Join(fieldProbes, "\n\t")))
+		}
+
+		var rangeDone bool
+		for _, pf := range pfs {
+			filter := "f." + pf._name
+			var put string
+			switch {
+			case (pf.isRangeGte || pf.isRangeLte) && intervals:
+				if rangeDone {
+					continue
+				}
+				rangeDone = true
+				gte, lte := "f._"+field._originalName+"Gte", "f._"+field._originalName+"Lte"
+				indexConds = append(indexConds, fmt.Sprintf("if %s != nil || %s != nil {\n\tix.%s.put(%s, %s, slot, add)\n\tconds++\n}",
+					gte, lte, intervalsName, gte, lte))
+				continue
+			case pf._kind == _qfKindExact && buckets:
+				put = fmt.Sprintf("ix.%s.put([]%s{*%s}, slot, add)", bucketsName, field._goType, filter)
+			case pf._kind == _qfKindMultiValue && buckets:
+				put = fmt.Sprintf("ix.%s.put(*%s, slot, add)", bucketsName, filter)
+			case pf._kind == _qfKindExact && intervals:
+				put = fmt.Sprintf("ix.%s.putEach([]%s{*%s}, slot, add)", intervalsName, field._goType, filter)
+			case pf._kind == _qfKindMultiValue && intervals:
+				put = fmt.Sprintf("ix.%s.putEach(*%s, slot, add)", intervalsName, filter)
+			default:
+				residual = append(residual, filter+" != nil")
+				continue
+			}
+			indexConds = append(indexConds, fmt.Sprintf("if %s != nil {\n\t%s\n\tconds++\n}", filter, put))
+		}
+	}
+	if len(residual) == 0 {
+		residual = []string{"false"}
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$subIndex":    subscriptionIndexName(origStructName),
+		"$origStruct":  origStructName,
+		"$structName":  structName,
+		"$indexFields": strings.Join(indexFields, "\n"),
+		"$initFields":  strings.Join(initFields, "\n"),
+		"$probes":      strings.Join(probes, "\n"),
+		"$builds":      strings.Join(builds, "\n"),
+		"$indexConds":  strings.Join(indexConds, "\n"),
+		"$residual":    strings.Join(residual, " || "),
+	})
+}
+
+const filterSubscriptionFuncs = `
+type _filterSub[K comparable, F any] struct {
+	id     K
+	filter F
+	// conds is the number of indexed conditions, residual is set when the filter has others.
+	conds    int
+	residual bool
+}
+
+// _filterBuckets is a hash index of exact and multi-value conditions, holding the slots of the
+// filters by the values they accept.
+type _filterBuckets[T comparable] map[T][]int
+
+func (b _filterBuckets[T]) put(values []T, slot int, add bool) {
+	for i, v := range values {
+		switch {
+		case slices.Contains(values[:i], v):
+			// a filter is hit once per condition
+		case add:
+			b[v] = append(b[v], slot)
+		default:
+			if b[v] = slices.DeleteFunc(b[v], func(s int) bool { return s == slot }); len(b[v]) == 0 {
+				delete(b, v)
+			}
+		}
+	}
+}
+
+func (b _filterBuckets[T]) probe(x T, hits map[int]int) {
+	for _, slot := range b[x] {
+		hits[slot]++
+	}
+}
+
+type _filterInterval[T any] struct {
+	lo, hi     T
+	noLo, noHi bool
+	slot       int
+}
+
+// _filterIntervals is an interval tree of range conditions. Intervals are collected by put and the
+// tree is built from them by build, after changes.
+type _filterIntervals[T any] struct {
+	cmp  func(a, b T) int
+	all  []_filterInterval[T]
+	root *_filterIntervalNode[T]
+}
+
+func (s *_filterIntervals[T]) put(lo, hi *T, slot int, add bool) {
+	if !add {
+		s.all = slices.DeleteFunc(s.all, func(iv _filterInterval[T]) bool { return iv.slot == slot })
+		return
+	}
+	if lo != nil && hi != nil && s.cmp(*lo, *hi) > 0 {
+		// an empty range is never hit, so its filter never matches
+		return
+	}
+	iv := _filterInterval[T]{noLo: lo == nil, noHi: hi == nil, slot: slot}
+	if lo != nil {
+		iv.lo = *lo
+	}
+	if hi != nil {
+		iv.hi = *hi
+	}
+	s.all = append(s.all, iv)
+}
+
+// putEach puts an interval holding a single value per distinct value, for exact and multi-value conditions.
+func (s *_filterIntervals[T]) putEach(values []T, slot int, add bool) {
+	for i := range values {
+		if !slices.ContainsFunc(values[:i], func(v T) bool { return s.cmp(v, values[i]) == 0 }) {
+			s.put(&values[i], &values[i], slot, add)
+		}
+	}
+}
+
+func (s *_filterIntervals[T]) build() {
+	s.root = _filterBuildIntervals(slices.Clone(s.all), s.cmp)
+}
+
+func (s *_filterIntervals[T]) probe(x T, hits map[int]int) {
+	for n := s.root; n != nil; {
+		switch c := s.cmp(x, n.center); {
+		case c < 0:
+			for _, iv := range n.byLo {
+				if !iv.noLo && s.cmp(iv.lo, x) > 0 {
+					break
+				}
+				hits[iv.slot]++
+			}
+			n = n.left
+		case c > 0:
+			for _, iv := range n.byHi {
+				if !iv.noHi && s.cmp(iv.hi, x) < 0 {
+					break
+				}
+				hits[iv.slot]++
+			}
+			n = n.right
+		default:
+			for _, iv := range n.byLo {
+				hits[iv.slot]++
+			}
+			n = nil
+		}
+	}
+}
+
+// _filterIntervalNode is a node of a centered interval tree: it holds the intervals containing its
+// center, sorted by their lower and by their upper bounds, the intervals below and above it are
+// in its subtrees.
+type _filterIntervalNode[T any] struct {
+	center      T
+	byLo, byHi  []_filterInterval[T]
+	left, right *_filterIntervalNode[T]
+}
+
+func _filterBuildIntervals[T any](ivs []_filterInterval[T], cmp func(a, b T) int) *_filterIntervalNode[T] {
+	if len(ivs) == 0 {
+		return nil
+	}
+	ends := make([]T, 0, 2*len(ivs))
+	for _, iv := range ivs {
+		if !iv.noLo {
+			ends = append(ends, iv.lo)
+		}
+		if !iv.noHi {
+			ends = append(ends, iv.hi)
+		}
+	}
+	slices.SortFunc(ends, cmp)
+	// every interval has a bound, the one of the center stays in this node, so subtrees are smaller
+	n := &_filterIntervalNode[T]{center: ends[len(ends)/2]}
+	var left, right []_filterInterval[T]
+	for _, iv := range ivs {
+		switch {
+		case !iv.noHi && cmp(iv.hi, n.center) < 0:
+			left = append(left, iv)
+		case !iv.noLo && cmp(iv.lo, n.center) > 0:
+			right = append(right, iv)
+		default:
+			n.byLo = append(n.byLo, iv)
+		}
+	}
+	n.byHi = slices.Clone(n.byLo)
+	slices.SortFunc(n.byLo, func(a, b _filterInterval[T]) int {
+		if a.noLo || b.noLo {
+			return _filterUnboundedFirst(a.noLo, b.noLo)
+		}
+		return cmp(a.lo, b.lo)
+	})
+	slices.SortFunc(n.byHi, func(a, b _filterInterval[T]) int {
+		if a.noHi || b.noHi {
+			return _filterUnboundedFirst(a.noHi, b.noHi)
+		}
+		return cmp(b.hi, a.hi)
+	})
+	n.left = _filterBuildIntervals(left, cmp)
+	n.right = _filterBuildIntervals(right, cmp)
+	return n
+}
+
+func _filterUnboundedFirst(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	}
+	return 1
+}
+`
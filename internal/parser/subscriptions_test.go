@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_subscriptionIndexing(t *testing.T) {
+	t.Parallel()
+
+	kinds := []queryFilterKind{_qfKindExact, _qfKindMultiValue, _qfKindRange}
+	tests := []struct {
+		name          string
+		field         _field
+		wantBuckets   bool
+		wantIntervals bool
+	}{
+		{name: "number", field: _field{_goType: "uint64", _qf: utiQueryFilter{_kindList: kinds}}, wantBuckets: true, wantIntervals: true},
+		{name: "exact string", field: _field{_goType: "string", _qf: utiQueryFilter{_kindList: kinds[:1]}}, wantBuckets: true},
+		{name: "time", field: _field{_goType: "time.Time", _qf: utiQueryFilter{_kindList: kinds[:1]}}, wantIntervals: true},
+		{name: "comparer", field: _field{_goType: "netip.Addr", _type: &fieldType{_comparer: true}, _qf: utiQueryFilter{_kindList: kinds}}, wantIntervals: true},
+		{name: "decimal", field: _field{_goType: "string", _qf: utiQueryFilter{_kindList: kinds, _decimal: true}}},
+		{name: "slice", field: _field{_goType: "string", _slice: true, _qf: utiQueryFilter{_kindList: []queryFilterKind{_qfKindAny}}}},
+	}
+	for _, test := range tests {
+		// Act
+		buckets, intervals := subscriptionIndexing(test.field)
+
+		// Assert
+		require.Equal(t, test.wantBuckets, buckets, test.name)
+		require.Equal(t, test.wantIntervals, intervals, test.name)
+	}
+}
+
+func Test_generateSubscriptionIndex(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{
+		_originalName: "Price",
+		_goType:       "float64",
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindRange, _qfKindExact}, _key: "price"},
+	}, {
+		_originalName: "Tags",
+		_goType:       "string",
+		_slice:        true,
+		_qf:           utiQueryFilter{_kindList: []queryFilterKind{_qfKindAny}, _key: "tags"},
+	}}
+	_, fieldMap := generateFilterStructDef("_ProductFilter", fields)
+
+	// Act
+	got := generateSubscriptionIndex("_ProductFilter", "Product", fields, fieldMap)
+
+	// Assert
+	require.Contains(t, got, "type ProductSubscriptionIndex[K comparable] struct {")
+	require.Contains(t, got, "_PriceBuckets _filterBuckets[float64]\n_PriceIntervals *_filterIntervals[float64]")
+	require.Contains(t, got, "_PriceIntervals: &_filterIntervals[float64]{cmp: cmp.Compare[float64]},")
+	require.Contains(t, got, "if x, ok := _PriceValue(v); ok {\n\tix._PriceBuckets.probe(x, hits)\n\tix._PriceIntervals.probe(x, hits)\n}")
+	require.Contains(t, got, "if f._PriceGte != nil || f._PriceLte != nil {\n\tix._PriceIntervals.put(f._PriceGte, f._PriceLte, slot, add)\n\tconds++\n}")
+	require.Contains(t, got, "residual := f._TagsAny != nil")
+}
+
+func TestSubscriptionIndex(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tPrice float64  `ufi:\"kind=range,exact;key=price\"`\n" +
+		"\tName  string   `ufi:\"kind=exact,multi-value;key=name\"`\n" +
+		"\tTags  []string `ufi:\"kind=any;key=tags\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+func main() {
+	ix := NewProductSubscriptionIndex[string]()
+	add := func(id, query string) {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			panic(err)
+		}
+		ix.Add(id, f)
+	}
+	match := func(v Product) {
+		ids := ix.Match(&v)
+		slices.Sort(ids)
+		fmt.Println(v.Price, v.Name, v.Tags, ids)
+	}
+
+	add("cheap", "price-to=10")
+	add("pricey", "price-from=100")
+	add("mid", "price-from=10&price-to=100")
+	add("exact", "price=50&name=a")
+	add("names", "name=a,b")
+	add("tagged", "tags-any=x&price-from=1")
+	add("all", "")
+	lo, hi := 10.0, 5.0
+	ix.Add("empty", &_ProductFilter{_PriceGte: &lo, _PriceLte: &hi})
+	match(Product{Price: 50, Name: "a"})
+	match(Product{Price: 10, Name: "b", Tags: []string{"x"}})
+	match(Product{Price: 7, Name: "c"})
+	match(Product{Price: 1000})
+
+	// the slots of removed filters are reused
+	ix.Remove("mid")
+	ix.Remove("cheap")
+	add("reused", "price-from=40&price-to=60")
+	add("names", "name=c")
+	match(Product{Price: 50, Name: "a"})
+	match(Product{Price: 10, Name: "c"})
+	fmt.Println(ix.Len(), ix.Remove("mid"))
+
+	// the index agrees with matching every filter
+	rnd := rand.New(rand.NewPCG(1, 2))
+	queries := []string{"price-from=%d", "price-to=%d", "price-from=%d&price-to=%[1]d5", "price=%d", "name=n%d", "name=n%d,n1", "tags-any=t%d"}
+	filters := make(map[int]*_ProductFilter)
+	ids := NewProductSubscriptionIndex[int]()
+	mismatches := 0
+	for i := range 2000 {
+		id := rnd.IntN(100)
+		if rnd.IntN(4) == 0 {
+			delete(filters, id)
+			ids.Remove(id)
+		} else {
+			f, err := ParseFiltersQuery(fmt.Sprintf(queries[rnd.IntN(len(queries))], rnd.IntN(10)))
+			if err != nil {
+				panic(err)
+			}
+			filters[id] = f
+			ids.Add(id, f)
+		}
+		v := Product{Price: float64(rnd.IntN(20)), Name: fmt.Sprintf("n%d", rnd.IntN(10)), Tags: []string{fmt.Sprintf("t%d", i%10)}}
+		var want []int
+		for id, f := range filters {
+			if f.Match(&v) {
+				want = append(want, id)
+			}
+		}
+		got := ids.Match(&v)
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(want, got) {
+			mismatches++
+		}
+	}
+	fmt.Println("mismatches", mismatches)
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `50 a [] [all exact mid names]
+10 b [x] [all cheap mid names tagged]
+7 c [] [all cheap]
+1000  [] [all pricey]
+50 a [] [all exact reused]
+10 c [] [all names]
+7 false
+mismatches 0
+`, got)
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateTextKind checks that the text kind is used on string fields and has a key of its own.
+func validateTextKind(field _field) error {
+	if !hasKind(field, _qfKindText) {
+		return nil
+	}
+	if field._slice || field._map || fieldBasicType(field) != "string" || field._qf._parser != "" ||
+		(field._type != nil && field._type._textUnmarshaler) {
+		return fmt.Errorf("text kind requires a string field")
+	}
+	if hasKind(field, _qfKindExact) || hasKind(field, _qfKindMultiValue) {
+		return fmt.Errorf("text kind shares its key with exact and multi-value kinds, they cannot be combined")
+	}
+	return nil
+}
+
+// fieldText returns an expression converting the value x of a text field to a string.
+func fieldText(field _field, x string) string {
+	return ternary(field._goType == "string", x, "string("+x+")")
+}
+
+// generateTextParser generates the parsing of a text query into its terms. Queries of stopwords only
+// leave the filter unset.
+func generateTextParser(variable string, field _field, pf parserField, qfKeyConstName string) string {
+	const tmpl = `
+if q.Has($key) {
+	$keyRaw := q.Get($key)
+	$validate
+	if terms := _filterTerms($keyRaw, o.stopwords); len(terms) > 0 {
+		$var.$parserField = &terms
+	}
+}
+`
+	var validate string
+	if len(fieldConstraints(field)) > 0 {
+		raw := ternary(field._goType == "string", qfKeyConstName+"Raw", field._goType+"("+qfKeyConstName+"Raw)")
+		validate = generateValidatorCall(field, pf, qfKeyConstName, raw)
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$key":         qfKeyConstName,
+		"$validate":    validate,
+		"$var":         variable,
+		"$parserField": pf._name,
+	})
+}
+
+func textIndexName(origStructName string) string {
+	return origStructName + "TextIndex"
+}
+
+// generateTextIndex generates an inverted index of the text fields of the filtered struct,
+// empty when there are none.
+func generateTextIndex(structName, origStructName string, fields []_field, structFieldMap map[string][]parserField) string {
+	const tmpl = `
+// $textIndex is an inverted index of the text filtered fields of a slice of $origStruct. It is built once
+// and narrows text filters down to the items holding every term, the other filters are matched
+// on those only. It must be rebuilt when the slice changes.
+type $textIndex struct {
+	items []$origStruct
+	$termFields
+}
+
+// New$textIndex indexes the text filtered fields of items.
+func New$textIndex(items []$origStruct) *$textIndex {
+	ix := &$textIndex{items: items}
+	for i := range items {
+		$addTerms
+	}
+	return ix
+}
+
+// Search returns the positions of the items that pass the filters, in order.
+func (ix *$textIndex) Search(f *$structName) []int {
+	var candidates []int
+	narrowed := false
+	$narrow
+	var res []int
+	visit := func(i int) {
+		if f.Match(&ix.items[i]) {
+			res = append(res, i)
+		}
+	}
+	if narrowed {
+		for _, i := range candidates {
+			visit(i)
+		}
+		return res
+	}
+	for i := range ix.items {
+		visit(i)
+	}
+	return res
+}
+
+// Apply returns the items that pass the filters, like $structName.Apply does without the index.
+func (ix *$textIndex) Apply(f *$structName) []$origStruct {
+	var res []$origStruct
+	for _, i := range ix.Search(f) {
+		res = append(res, ix.items[i])
+	}
+	return res
+}
+`
+	const indexTmpl = `if x, ok := $valueFunc(&items[i]); ok {
+	ix.$terms = _filterIndexTerms(ix.$terms, $text, i)
+}`
+	const narrowTmpl = `if f.$parserField != nil {
+	candidates, narrowed = _filterPostings(ix.$terms, *f.$parserField, candidates, narrowed)
+}`
+	var termFields, indexFields, narrow []string
+	for _, field := range fields {
+		for _, pf := range structFieldMap[field._originalName] {
+			if pf._kind != _qfKindText {
+				continue
+			}
+			terms := "_" + field._originalName + "Terms"
+			termFields = append(termFields, terms+" map[string][]int")
+			indexFields = append(indexFields, namedReplace(indexTmpl, map[string]string{
+				"$valueFunc": fieldValueFuncName(field),
+				"$terms":     terms,
+				"$text":      fieldText(field, "x"),
+			}))
+			narrow = append(narrow, namedReplace(narrowTmpl, map[string]string{
+				"$parserField": pf._name,
+				"$terms":       terms,
+			}))
+		}
+	}
+	if len(termFields) == 0 {
+		return ""
+	}
+	return namedReplace(tmpl, map[string]string{
+		"$textIndex":  textIndexName(origStructName),
+		"$origStruct": origStructName,
+		"$structName": structName,
+		"$termFields": strings.Join(termFields, "\n"),
+		"$addTerms":   strings.Join(indexFields, "\n"),
+		"$narrow":     strings.Join(narrow, "\n"),
+	})
+}
+
+const filterTextFuncs = `
+// FilterStopwords are left out of text queries unless WithFilterStopwords sets others.
+var FilterStopwords = []string{"a", "an", "and", "are", "as", "at", "be", "by", "for", "from", "in", "is", "it", "of", "on", "or", "the", "to", "with"}
+
+// WithFilterStopwords sets the words left out of text queries, none when called without words.
+func WithFilterStopwords(words ...string) FilterOption {
+	return func(o *filterOptions) {
+		o.stopwords = append([]string{}, words...)
+	}
+}
+
+// _filterTerms splits s into lower cased terms of letters and digits, leaving out stopwords,
+// FilterStopwords when stopwords is nil, and repeated terms.
+func _filterTerms(s string, stopwords []string) []string {
+	if stopwords == nil {
+		stopwords = FilterStopwords
+	}
+	var terms []string
+	for _, term := range _filterTokens(s) {
+		if !slices.Contains(stopwords, term) && !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func _filterTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// _filterHasTerms reports whether s holds every term.
+func _filterHasTerms(s string, terms []string) bool {
+	tokens := _filterTokens(s)
+	for _, term := range terms {
+		if !slices.Contains(tokens, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// _filterIndexTerms adds the position i of s to the postings of its terms. Items are indexed
+// in order, so postings stay sorted.
+func _filterIndexTerms(postings map[string][]int, s string, i int) map[string][]int {
+	if postings == nil {
+		postings = make(map[string][]int)
+	}
+	for _, token := range _filterTokens(s) {
+		if p := postings[token]; len(p) == 0 || p[len(p)-1] != i {
+			postings[token] = append(p, i)
+		}
+	}
+	return postings
+}
+
+// _filterPostings intersects candidates with the postings of every term. Candidates are all
+// positions until narrowed is set.
+func _filterPostings(postings map[string][]int, terms []string, candidates []int, narrowed bool) ([]int, bool) {
+	for _, term := range terms {
+		p := postings[term]
+		if !narrowed {
+			candidates, narrowed = p, true
+			continue
+		}
+		var res []int
+		for i, j := 0, 0; i < len(candidates) && j < len(p); {
+			switch {
+			case candidates[i] < p[j]:
+				i++
+			case candidates[i] > p[j]:
+				j++
+			default:
+				res = append(res, candidates[i])
+				i++
+				j++
+			}
+		}
+		candidates = res
+	}
+	return candidates, narrowed
+}`
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateTextKind(t *testing.T) {
+	t.Parallel()
+
+	kinds := func(kinds ...queryFilterKind) utiQueryFilter {
+		return utiQueryFilter{_kindList: kinds}
+	}
+	require.NoError(t, validateTextKind(_field{_goType: "string", _qf: kinds(_qfKindText)}))
+	require.NoError(t, validateTextKind(_field{_goType: "Title", _type: &fieldType{_underlying: "string"}, _qf: kinds(_qfKindText)}))
+	require.NoError(t, validateTextKind(_field{_goType: "int", _qf: kinds(_qfKindExact)}))
+	require.Error(t, validateTextKind(_field{_goType: "int", _qf: kinds(_qfKindText)}))
+	require.Error(t, validateTextKind(_field{_goType: "string", _slice: true, _qf: kinds(_qfKindText)}))
+	require.Error(t, validateTextKind(_field{_goType: "string", _qf: kinds(_qfKindText, _qfKindExact)}))
+}
+
+func Test_generateTextIndex(t *testing.T) {
+	t.Parallel()
+
+	fields := []_field{{_originalName: "Description", _goType: "string"}, {_originalName: "Name", _goType: "string"}}
+	structFieldMap := map[string][]parserField{
+		"Description": {{_name: "_DescriptionText", _kind: _qfKindText}},
+		"Name":        {{_name: "_NameExact", _kind: _qfKindExact}},
+	}
+
+	// Act
+	got := generateTextIndex("_ProductFilter", "Product", fields, structFieldMap)
+
+	// Assert
+	require.Contains(t, got, "type ProductTextIndex struct {")
+	require.Contains(t, got, "_DescriptionTerms map[string][]int")
+	require.Contains(t, got, "candidates, narrowed = _filterPostings(ix._DescriptionTerms, *f._DescriptionText, candidates, narrowed)")
+	require.NotContains(t, got, "_NameTerms")
+	require.Empty(t, generateTextIndex("_ProductFilter", "Product", fields[1:], structFieldMap))
+}
+
+func Test_generateTextParser(t *testing.T) {
+	t.Parallel()
+
+	field := _field{_originalName: "Description", _goType: "string"}
+
+	// Act
+	got := generateTextParser("res", field, parserField{_name: "_DescriptionText", _kind: _qfKindText}, "_DescriptionKey")
+
+	// Assert
+	require.Contains(t, got, "if terms := _filterTerms(_DescriptionKeyRaw, o.stopwords); len(terms) > 0 {")
+	require.True(t, strings.Contains(got, "res._DescriptionText = &terms"))
+}
+
+func TestTextIndex(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tTitle string  `ufi:\"kind=text;key=q\"`\n" +
+		"\tBrand string  `ufi:\"kind=text;key=brand\"`\n" +
+		"\tPrice float64 `ufi:\"kind=range;key=price\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func main() {
+	items := []Product{
+		{Title: "The Red Shoe", Brand: "Acme Co.", Price: 10},
+		{Title: "red-shoe, the sequel", Brand: "Zeta", Price: 30},
+		{Title: "Blue shoe", Brand: "acme", Price: 20},
+		{Title: "Red hat", Brand: "Acme", Price: 40},
+	}
+	ix := NewProductTextIndex(items)
+	for _, test := range []struct {
+		query string
+		opts  []FilterOption
+	}{
+		{"q=red+shoe", nil},
+		{"q=RED&brand=acme", nil},
+		{"q=shoe&price-from=15", nil},
+		{"q=the", nil},
+		{"q=the", []FilterOption{WithFilterStopwords()}},
+		{"q=shoes", nil},
+		{"price-to=20", nil},
+	} {
+		f, err := ParseFiltersQuery(test.query, test.opts...)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(test.query, ix.Search(f), reflect.DeepEqual(ix.Apply(f), f.Apply(items)))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `q=red+shoe [0 1] true
+q=RED&brand=acme [0 3] true
+q=shoe&price-from=15 [1 2] true
+q=the [0 1 2 3] true
+q=the [0 1] true
+q=shoes [] true
+price-to=20 [0 2] true
+`, got)
+}
@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func timeLocationVarName(field _field) string {
+	return "_" + field._originalName + "Location"
+}
+
+// validateTimeOptions checks layout and tz tag options, which are only meaningful for time fields.
+func validateTimeOptions(field _field) error {
+	if field._goType != "time.Time" {
+		if field._qf._layout != "" || field._qf._timezone != "" {
+			return fmt.Errorf("layout and tz options are not supported for type %s", field._goType)
+		}
+		return nil
+	}
+	if field._qf._timezone != "" {
+		if _, err := time.LoadLocation(field._qf._timezone); err != nil {
+			return fmt.Errorf("invalid tz %q: %w", field._qf._timezone, err)
+		}
+	}
+	return nil
+}
+
+// generateTimeLocationVar generates the location of a field with the tz option.
+func generateTimeLocationVar(field _field) string {
+	if field._goType != "time.Time" || field._qf._timezone == "" {
+		return ""
+	}
+	return fmt.Sprintf("var %s = _filterLoadLocation(%s)", timeLocationVarName(field), strconv.Quote(field._qf._timezone))
+}
+
+func generateTimeParseCall(field _field, pf parserField, rawVar string) string {
+	location := "o.location"
+	if field._qf._timezone != "" {
+		location = timeLocationVarName(field)
+	}
+	layout := strconv.Quote(field._qf._layout)
+	if isListKind(pf._kind) {
+		return fmt.Sprintf("gsliceparse(%s, func(inp string) (time.Time, error) { return vtimeparse(inp, o.now, %s, %s, false) })", rawVar, location, layout)
+	}
+	return fmt.Sprintf("vtimeparse(%s, o.now, %s, %s, %t)", rawVar, location, layout, pf.isRangeLte)
+}
+
+func hasTimezones(fields []_field) bool {
+	for _, field := range fields {
+		if field._goType == "time.Time" && field._qf._timezone != "" {
+			return true
+		}
+	}
+	return false
+}
+
+const filterLoadLocationFunc = `
+func _filterLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("ufi: cannot load time zone %q: %v", name, err))
+	}
+	return loc
+}
+`
+
+const filterOptionsDef = `
+// FilterOption configures the ParseFilters functions and the filter middleware.
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	location *time.Location
+	clock    func() time.Time
+	// now is read from clock once, so every relative time of a query resolves against the same moment.
+	now time.Time
+	// stopwords are left out of text queries, FilterStopwords when nil.
+	stopwords []string
+	// locale is the locale numbers are written in, nil for Go syntax.
+	locale *FilterLocale
+	// respond answers requests with invalid filters in the middleware.
+	respond FilterErrorResponder
+	// precedence decides between query and form body values in ParseFiltersRequest.
+	precedence FilterPrecedence
+	// goSyntax is set for values in Go syntax, such as those of JSON documents, which are read without locales.
+	goSyntax bool
+}
+
+// WithFilterLocation sets the time zone of time values sent without one, time.UTC by default.
+// Fields with the tz tag option always use their own time zone.
+func WithFilterLocation(loc *time.Location) FilterOption {
+	return func(o *filterOptions) {
+		o.location = loc
+	}
+}
+
+// WithFilterClock sets the clock relative time values such as now-7d are resolved against, time.Now by default.
+func WithFilterClock(now func() time.Time) FilterOption {
+	return func(o *filterOptions) {
+		o.clock = now
+	}
+}
+
+// numberLocale returns the locale numbers are written in: field, set by the locale tag option,
+// or the one of WithFilterLocale. It is nil for Go syntax.
+func (o *filterOptions) numberLocale(field *FilterLocale) *FilterLocale {
+	if o.goSyntax {
+		return nil
+	}
+	if field != nil {
+		return field
+	}
+	return o.locale
+}
+
+func newFilterOptions(opts []FilterOption) *filterOptions {
+	o := &filterOptions{location: time.UTC, clock: time.Now, respond: RespondFilterError}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.now = o.clock()
+	return o
+}
+`
+
+const timeParseFunc = `
+// _filterTimeLayouts are tried in order for time fields without the layout tag option.
+var _filterTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", time.DateTime, time.DateOnly}
+
+// vtimeparse parses inp as a relative time, see vtimerelative, then with layout or, when it is empty,
+// with _filterTimeLayouts and as a unix timestamp, in seconds or, for more than 10 digits, in milliseconds.
+// Values without a zone are read in loc. Date-only upper bounds are moved to the end of the day,
+// so the whole day is included.
+func vtimeparse(inp string, now time.Time, loc *time.Location, layout string, upper bool) (time.Time, error) {
+	if v, ok := vtimerelative(inp, now.In(loc), upper); ok {
+		return v, nil
+	}
+	layouts := _filterTimeLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		v, err := time.ParseInLocation(l, inp, loc)
+		if err != nil {
+			if layout != "" {
+				return time.Time{}, err
+			}
+			continue
+		}
+		if upper && !strings.ContainsAny(l, "345") {
+			v = v.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return v, nil
+	}
+	if ts, err := strconv.ParseInt(inp, 10, 64); err == nil {
+		if len(strings.TrimPrefix(inp, "-")) > 10 {
+			return time.UnixMilli(ts).In(loc), nil
+		}
+		return time.Unix(ts, 0).In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time", inp)
+}
+
+var _filterTimeAnchors = map[string]func(now time.Time) time.Time{
+	"now":          func(now time.Time) time.Time { return now },
+	"today":        _filterStartOfDay,
+	"yesterday":    func(now time.Time) time.Time { return _filterStartOfDay(now).AddDate(0, 0, -1) },
+	"tomorrow":     func(now time.Time) time.Time { return _filterStartOfDay(now).AddDate(0, 0, 1) },
+	"startOfDay":   _filterStartOfDay,
+	"startOfWeek":  _filterStartOfWeek,
+	"startOfMonth": _filterStartOfMonth,
+	"startOfYear":  _filterStartOfYear,
+	"endOfDay":     func(now time.Time) time.Time { return _filterStartOfDay(now).AddDate(0, 0, 1).Add(-time.Nanosecond) },
+	"endOfWeek":    func(now time.Time) time.Time { return _filterStartOfWeek(now).AddDate(0, 0, 7).Add(-time.Nanosecond) },
+	"endOfMonth":   func(now time.Time) time.Time { return _filterStartOfMonth(now).AddDate(0, 1, 0).Add(-time.Nanosecond) },
+	"endOfYear":    func(now time.Time) time.Time { return _filterStartOfYear(now).AddDate(1, 0, 0).Add(-time.Nanosecond) },
+}
+
+// _filterDayAnchors stand for a whole day, as upper bounds they include it like date-only values.
+var _filterDayAnchors = map[string]bool{"today": true, "yesterday": true, "tomorrow": true}
+
+func _filterStartOfDay(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// _filterStartOfWeek returns the start of the week, weeks start on Monday.
+func _filterStartOfWeek(now time.Time) time.Time {
+	day := _filterStartOfDay(now)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func _filterStartOfMonth(now time.Time) time.Time {
+	y, m, _ := now.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+}
+
+func _filterStartOfYear(now time.Time) time.Time {
+	return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+}
+
+// vtimerelative resolves an anchor from _filterTimeAnchors followed by offsets in seconds (s), minutes (m),
+// hours (h), days (d), weeks (w), months (M) or years (y), e.g. now-7d, today or startOfMonth-1M+2w.
+// Units following an offset without a sign share its sign, so now-1h30m is 90 minutes ago. A space is read as plus, since that is what an unescaped plus in a query becomes.
+func vtimerelative(inp string, now time.Time, upper bool) (time.Time, bool) {
+	i := 0
+	for i < len(inp) && (inp[i] >= 'a' && inp[i] <= 'z' || inp[i] >= 'A' && inp[i] <= 'Z') {
+		i++
+	}
+	anchor, ok := _filterTimeAnchors[inp[:i]]
+	if !ok {
+		return time.Time{}, false
+	}
+	v := anchor(now)
+	if upper && _filterDayAnchors[inp[:i]] {
+		v = v.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	rest := inp[i:]
+	sign := 0
+	for rest != "" {
+		switch rest[0] {
+		case '+', ' ':
+			sign, rest = 1, rest[1:]
+		case '-':
+			sign, rest = -1, rest[1:]
+		default:
+			// the units of a compound offset such as +1h30m share its sign
+			if sign == 0 {
+				return time.Time{}, false
+			}
+		}
+		j := 0
+		for j < len(rest) && rest[j] >= '0' && rest[j] <= '9' {
+			j++
+		}
+		if j == 0 || j == len(rest) {
+			return time.Time{}, false
+		}
+		n, err := strconv.Atoi(rest[:j])
+		if err != nil {
+			return time.Time{}, false
+		}
+		n *= sign
+		switch rest[j] {
+		case 's':
+			v = v.Add(time.Duration(n) * time.Second)
+		case 'm':
+			v = v.Add(time.Duration(n) * time.Minute)
+		case 'h':
+			v = v.Add(time.Duration(n) * time.Hour)
+		case 'd':
+			v = v.AddDate(0, 0, n)
+		case 'w':
+			v = v.AddDate(0, 0, 7*n)
+		case 'M':
+			v = v.AddDate(0, n, 0)
+		case 'y':
+			v = v.AddDate(n, 0, 0)
+		default:
+			return time.Time{}, false
+		}
+		rest = rest[j+1:]
+	}
+	return v, true
+}
+`
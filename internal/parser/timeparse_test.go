@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateTimeParseCall(t *testing.T) {
+	t.Parallel()
+
+	field := _field{
+		_originalName: "CreatedAt",
+		_goType:       "time.Time",
+		_qf:           utiQueryFilter{_layout: "02.01.2006"},
+	}
+
+	// Act
+	gotLte := generateTimeParseCall(field, parserField{_kind: _qfKindRange, isRangeLte: true}, "raw")
+	field._qf._timezone = "Europe/Berlin"
+	gotMulti := generateTimeParseCall(field, parserField{_kind: _qfKindMultiValue}, "raw")
+
+	// Assert
+	require.Equal(t, `vtimeparse(raw, o.now, o.location, "02.01.2006", true)`, gotLte)
+	require.Equal(t, `gsliceparse(raw, func(inp string) (time.Time, error) { return vtimeparse(inp, o.now, _CreatedAtLocation, "02.01.2006", false) })`, gotMulti)
+}
+
+func Test_validateTimeOptions(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validateTimeOptions(_field{_goType: "time.Time", _qf: utiQueryFilter{_timezone: "Europe/Berlin"}}))
+	require.Error(t, validateTimeOptions(_field{_goType: "time.Time", _qf: utiQueryFilter{_timezone: "Nowhere/City"}}))
+	require.Error(t, validateTimeOptions(_field{_goType: "uint64", _qf: utiQueryFilter{_layout: "2006"}}))
+}
+
+func TestRelativeTimes(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\nimport \"time\"\n\ntype Product struct {\n" +
+		"\tCreated time.Time `ufi:\"kind=range;key=created\"`\n" +
+		"\tLocal   time.Time `ufi:\"kind=range;key=local;tz=America/New_York\"`\n}\n"
+	const main = `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	clock := WithFilterClock(func() time.Time { return time.Date(2025, 3, 12, 15, 4, 5, 0, time.UTC) })
+	tokyo := WithFilterLocation(time.FixedZone("JST", 9*3600))
+	for _, test := range []struct {
+		query string
+		opts  []FilterOption
+	}{
+		{"created-from=now-1h30m&created-to=now+1h30m+1d", nil},
+		{"created-from=now 2h", nil},
+		{"created-from=today&created-to=today", nil},
+		{"created-from=startOfWeek&created-to=endOfMonth", nil},
+		{"created-from=startOfMonth-1M+2w&created-to=2025-03-01", nil},
+		{"created-from=today&created-to=2025-03-20", []FilterOption{tokyo}},
+		{"local-from=today&local-to=2025-03-20", nil},
+		{"created-from=now1h", nil},
+		{"created-from=now-1x", nil},
+	} {
+		f, err := ParseFiltersQuery(test.query, append(test.opts, clock)...)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(f.GetCreatedGte().Format(time.RFC3339Nano), f.GetCreatedLte().Format(time.RFC3339Nano),
+			f.GetLocalGte().Format(time.RFC3339Nano), f.GetLocalLte().Format(time.RFC3339Nano))
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	const zero = "0001-01-01T00:00:00Z"
+	require.Equal(t, `2025-03-12T13:34:05Z 2025-03-13T16:34:05Z `+zero+` `+zero+`
+2025-03-12T17:04:05Z `+zero+` `+zero+` `+zero+`
+2025-03-12T00:00:00Z 2025-03-12T23:59:59.999999999Z `+zero+` `+zero+`
+2025-03-10T00:00:00Z 2025-03-31T23:59:59.999999999Z `+zero+` `+zero+`
+2025-02-15T00:00:00Z 2025-03-01T23:59:59.999999999Z `+zero+` `+zero+`
+2025-03-13T00:00:00+09:00 2025-03-20T23:59:59.999999999+09:00 `+zero+` `+zero+`
+`+zero+` `+zero+` 2025-03-12T00:00:00-04:00 2025-03-20T23:59:59.999999999-04:00
+filter "created-from" (field Created): invalid value "now1h": cannot parse "now1h" as time
+filter "created-from" (field Created): invalid value "now-1x": cannot parse "now-1x" as time
+`, got)
+}
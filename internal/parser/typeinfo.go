@@ -0,0 +1,306 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fieldType describes what the generator knows about a field type beyond its name.
+type fieldType struct {
+	// _underlying is the built-in type a named type converts to, empty if there is none.
+	_underlying string
+	// _textUnmarshaler is set when a pointer to the type implements encoding.TextUnmarshaler.
+	_textUnmarshaler bool
+	// _comparer is set when the type has a Compare(T) int method ordering its values.
+	_comparer bool
+	// _enum holds the values of the constants declared with the named type, in declaration order.
+	_enum []string
+}
+
+// pathStep is a struct field on the way from the filtered struct to a filtered value.
+type pathStep struct {
+	_name string
+	// _ptr is set when the field is a pointer, it is nil checked before it is followed.
+	_ptr bool
+	// _embedded steps are left out of names and keys, like Go promotes the fields of embedded structs.
+	_embedded bool
+}
+
+// loadStructFields type checks the package in dir and collects the tagged fields of the struct,
+// including the fields of nested and embedded structs, see collectStructFields.
+// The output file is skipped, it may be stale. Type errors are ignored, since the package usually
+// refers to the code that is about to be generated.
+func loadStructFields(dir, outputFile, structName string) ([]_field, error) {
+	absOutput, err := filepath.Abs(outputFile)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(path); abs == absOutput {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no go files in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	obj := pkg.Scope().Lookup(structName)
+	if obj == nil {
+		return nil, fmt.Errorf("struct %s not found", structName)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", structName)
+	}
+
+	var fields []_field
+	if err := collectStructFields(pkg, st, nil, "", "", map[*types.Struct]bool{st: true}, &fields); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field._originalName] {
+			return nil, fmt.Errorf("field %s is declared more than once", fieldDisplayName(field))
+		}
+		seen[field._originalName] = true
+	}
+	return fields, nil
+}
+
+// collectStructFields appends the fields of st that have a ufi tag. Embedded structs and struct fields
+// tagged without a kind are walked: their keys are prefixed with the key of the field and a dot,
+// e.g. dimensions.weight, and their columns with its column and an underscore, or nothing when
+// the column ends with a dot, e.g. customer.country for column=customer.
+func collectStructFields(pkg *types.Package, st *types.Struct, path []pathStep, keyPrefix, columnPrefix string, walking map[*types.Struct]bool, fields *[]_field) error {
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag, tagged := reflect.StructTag(st.Tag(i)).Lookup(_tagName)
+		if !tagged && !v.Embedded() {
+			continue
+		}
+		qf := parseFilterTag(st.Tag(i))
+
+		t := v.Type()
+		step := pathStep{_name: v.Name(), _embedded: v.Embedded()}
+		if ptr, ok := t.(*types.Pointer); ok {
+			t, step._ptr = ptr.Elem(), true
+		}
+		steps := append(path[:len(path):len(path)], step)
+		var slice, isMap bool
+		if sl, ok := t.(*types.Slice); ok && len(qf._kindList) > 0 {
+			t, slice = sl.Elem(), true
+		}
+		if m, ok := t.(*types.Map); ok && len(qf._kindList) > 0 && types.Identical(m.Key(), types.Typ[types.String]) {
+			t, isMap = m.Elem(), true
+		}
+
+		if nested := nestedStruct(t, qf); nested != nil {
+			if walking[nested] {
+				return fmt.Errorf("field %s: recursive struct %s", v.Name(), types.TypeString(t, qualifier))
+			}
+			walking[nested] = true
+			key, column := keyPrefix, columnPrefix
+			if qf._key != "" {
+				key += qf._key + "."
+			}
+			switch {
+			case qf._column != "":
+				column += qf._column
+				if !strings.HasSuffix(qf._column, ".") {
+					column += "_"
+				}
+			case !v.Embedded():
+				column += snakeCase(v.Name()) + "_"
+			}
+			if err := collectStructFields(pkg, nested, steps, key, column, walking, fields); err != nil {
+				return err
+			}
+			delete(walking, nested)
+			continue
+		}
+		if !tagged || tag == "" {
+			continue
+		}
+
+		var lon *lonField
+		if qf._lon != "" {
+			var err error
+			if lon, err = siblingLonField(st, qf._lon, columnPrefix); err != nil {
+				return fmt.Errorf("field %s: %w", v.Name(), err)
+			}
+		}
+		qf._key = keyPrefix + qf._key
+		qf._column = columnPrefix + ternary(qf._column != "", qf._column, snakeCase(v.Name()))
+		*fields = append(*fields, _field{
+			_originalName: pathName(steps, ""),
+			_goType:       types.TypeString(t, qualifier),
+			_tag:          st.Tag(i),
+			_qf:           qf,
+			_type:         describeType(t, pkg),
+			_path:         steps,
+			_slice:        slice,
+			_map:          isMap,
+			_lon:          lon,
+		})
+	}
+	return nil
+}
+
+// siblingLonField looks up the longitude field name of st, paired with a geo filtered latitude field.
+func siblingLonField(st *types.Struct, name, columnPrefix string) (*lonField, error) {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if v.Name() != name {
+			continue
+		}
+		basic, ok := v.Type().Underlying().(*types.Basic)
+		if !ok || basic.Info()&types.IsFloat == 0 {
+			return nil, fmt.Errorf("longitude field %s must be a float", name)
+		}
+		column := parseFilterTag(st.Tag(i))._column
+		return &lonField{_name: name, _column: columnPrefix + ternary(column != "", column, snakeCase(name))}, nil
+	}
+	return nil, fmt.Errorf("longitude field %s not found", name)
+}
+
+// nestedStruct returns the struct of t when its fields are filtered rather than t itself.
+func nestedStruct(t types.Type, qf utiQueryFilter) *types.Struct {
+	if len(qf._kindList) > 0 || qf._parser != "" {
+		return nil
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || describeType(t, nil)._textUnmarshaler {
+		return nil
+	}
+	return st
+}
+
+func describeType(t types.Type, pkg *types.Package) *fieldType {
+	ft := &fieldType{
+		_textUnmarshaler: hasMethod(types.NewPointer(t), "UnmarshalText", isTextUnmarshalerSignature),
+		_comparer: hasMethod(t, "Compare", func(sig *types.Signature) bool {
+			return sig.Params().Len() == 1 && types.Identical(sig.Params().At(0).Type(), t) &&
+				sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), types.Typ[types.Int])
+		}),
+	}
+	if named, ok := t.(*types.Named); ok {
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			if _, supported := goTypeParserFuncs[basic.Name()]; supported {
+				ft._underlying = basic.Name()
+				ft._enum = typedConstValues(named, pkg)
+			}
+		}
+	}
+	return ft
+}
+
+// typedConstValues returns the values of the constants of type t. Only types of pkg are enums,
+// constants of imported types such as time.Duration are units or samples rather than a value set.
+func typedConstValues(t *types.Named, pkg *types.Package) []string {
+	if t.Obj().Pkg() != pkg {
+		return nil
+	}
+	scope := pkg.Scope()
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if !ok || !types.Identical(c.Type(), t) {
+			continue
+		}
+		consts = append(consts, c)
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	var values []string
+	seen := make(map[string]struct{})
+	for _, c := range consts {
+		var value string
+		switch c.Val().Kind() {
+		case constant.String:
+			value = constant.StringVal(c.Val())
+		case constant.Float:
+			f, _ := constant.Float64Val(c.Val())
+			value = strconv.FormatFloat(f, 'g', -1, 64)
+		default:
+			value = c.Val().ExactString()
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		values = append(values, value)
+	}
+	return values
+}
+
+func hasMethod(t types.Type, name string, matches func(*types.Signature) bool) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	return matches(fn.Type().(*types.Signature))
+}
+
+func isTextUnmarshalerSignature(sig *types.Signature) bool {
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 {
+		return false
+	}
+	slice, ok := sig.Params().At(0).Type().(*types.Slice)
+	return ok && types.Identical(slice.Elem(), types.Typ[types.Byte]) &&
+		types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// fieldBasicType returns the built-in type values of the field are parsed and compared as.
+func fieldBasicType(field _field) string {
+	if _, builtin := goTypeParserFuncs[field._goType]; builtin {
+		return field._goType
+	}
+	if field._type != nil && field._type._underlying != "" {
+		return field._type._underlying
+	}
+	return field._goType
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_loadStructFields(t *testing.T) {
+	t.Parallel()
+
+	const src = `package sample
+
+import "time"
+
+type Product struct {
+	Name       string    ` + "`" + `ufi:"kind=exact;key=name"` + "`" + `
+	Rating     Rating    ` + "`" + `ufi:"kind=range;key=rating"` + "`" + `
+	Currency   Currency  ` + "`" + `ufi:"kind=exact;key=currency"` + "`" + `
+	Version    Version   ` + "`" + `ufi:"kind=range;key=version"` + "`" + `
+	Since      time.Time ` + "`" + `ufi:"kind=range;key=since"` + "`" + `
+	Kind       Kind      ` + "`" + `ufi:"kind=exact;key=kind"` + "`" + `
+	Dimensions *Dimensions ` + "`" + `ufi:"key=dim;column=d."` + "`" + `
+	Tags       []Kind ` + "`" + `ufi:"kind=any;key=tags"` + "`" + `
+	Untagged   int
+	Audit
+	Filter *_ProductFilter // declared by the generated file
+}
+
+type Dimensions struct {
+	Weight *float64 ` + "`" + `ufi:"kind=range;key=weight"` + "`" + `
+}
+
+type Audit struct {
+	Revision int ` + "`" + `ufi:"kind=range;key=revision"` + "`" + `
+}
+
+type Rating int
+
+type Kind string
+
+const (
+	KindB    Kind = "b"
+	KindA    Kind = "a"
+	KindAlso      = KindA
+	other         = "c"
+)
+
+type Currency string
+
+func (c *Currency) UnmarshalText(text []byte) error { return nil }
+
+type Version struct{ Major int }
+
+func (v *Version) UnmarshalText(text []byte) error { return nil }
+
+func (v Version) Compare(other Version) int { return v.Major - other.Major }
+`
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "product.go"), []byte(src), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ufi_product.go"), []byte("package sample\n\nstale!"), 0o644))
+
+	// Act
+	fields, err := loadStructFields(dir, filepath.Join(dir, "ufi_product.go"), "Product")
+
+	// Assert
+	require.NoError(t, err)
+	got := make(map[string]_field, len(fields))
+	var names []string
+	for _, field := range fields {
+		got[field._originalName] = field
+		names = append(names, field._originalName)
+	}
+	require.Equal(t, []string{"Name", "Rating", "Currency", "Version", "Since", "Kind", "DimensionsWeight", "Tags", "Revision"}, names)
+	require.Equal(t, &fieldType{}, got["Name"]._type)
+	require.Equal(t, &fieldType{_underlying: "int"}, got["Rating"]._type)
+	require.Equal(t, &fieldType{_underlying: "string", _textUnmarshaler: true}, got["Currency"]._type)
+	require.Equal(t, &fieldType{_textUnmarshaler: true, _comparer: true}, got["Version"]._type)
+	require.Equal(t, "time.Time", got["Since"]._goType)
+	require.Equal(t, &fieldType{_textUnmarshaler: true, _comparer: true}, got["Since"]._type)
+	require.Equal(t, &fieldType{_underlying: "string", _enum: []string{"b", "a"}}, got["Kind"]._type)
+
+	require.True(t, got["Tags"]._slice)
+	require.Equal(t, "Kind", got["Tags"]._goType)
+	require.Equal(t, []string{"b", "a"}, got["Tags"]._type._enum)
+
+	weight := got["DimensionsWeight"]
+	require.Equal(t, "float64", weight._goType)
+	require.Equal(t, "dim.weight", weight._qf._key)
+	require.Equal(t, "d.weight", weight._qf._column)
+	require.Equal(t, []pathStep{{_name: "Dimensions", _ptr: true}, {_name: "Weight", _ptr: true}}, weight._path)
+	require.Equal(t, "Dimensions.Weight", fieldDisplayName(weight))
+
+	revision := got["Revision"]
+	require.Equal(t, "revision", revision._qf._key)
+	require.Equal(t, "revision", revision._qf._column)
+	require.Equal(t, []pathStep{{_name: "Audit", _embedded: true}, {_name: "Revision"}}, revision._path)
+
+	_, err = loadStructFields(dir, filepath.Join(dir, "ufi_product.go"), "Missing")
+	require.Error(t, err)
+}
+
+func Test_loadStructFields_Recursive(t *testing.T) {
+	t.Parallel()
+
+	const src = "package sample\n\ntype Node struct {\n\tNext *Node `ufi:\"key=next\"`\n}\n"
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node.go"), []byte(src), 0o644))
+
+	// Act
+	_, err := loadStructFields(dir, filepath.Join(dir, "ufi_node.go"), "Node")
+
+	// Assert
+	require.ErrorContains(t, err, "recursive")
+}
+
+func Test_loadStructFields_Geo(t *testing.T) {
+	t.Parallel()
+
+	const src = "package sample\n\ntype Store struct {\n\tPlace Place `ufi:\"key=place;column=p.\"`\n}\n\n" +
+		"type Place struct {\n\tLat float64 `ufi:\"kind=geo;lon=Lng\"`\n\tLng float64 `ufi:\"column=longitude\"`\n}\n"
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store.go"), []byte(src), 0o644))
+
+	// Act
+	fields, err := loadStructFields(dir, filepath.Join(dir, "ufi_store.go"), "Store")
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, "PlaceLat", fields[0]._originalName)
+	require.Equal(t, &lonField{_name: "Lng", _column: "p.longitude"}, fields[0]._lon)
+	require.Equal(t, "p.lat", fields[0]._qf._column)
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type unitFamily struct {
+	_varName string
+	_units   map[string]int64
+}
+
+var (
+	_unitFamilyBytes = unitFamily{
+		_varName: "_filterByteUnits",
+		_units: map[string]int64{
+			"B": 1,
+			"K": 1e3, "KB": 1e3, "kB": 1e3, "M": 1e6, "MB": 1e6, "G": 1e9, "GB": 1e9, "T": 1e12, "TB": 1e12, "P": 1e15, "PB": 1e15,
+			"Ki": 1 << 10, "KiB": 1 << 10, "Mi": 1 << 20, "MiB": 1 << 20, "Gi": 1 << 30, "GiB": 1 << 30,
+			"Ti": 1 << 40, "TiB": 1 << 40, "Pi": 1 << 50, "PiB": 1 << 50,
+		},
+	}
+	_unitFamilyTime = unitFamily{
+		_varName: "_filterTimeUnits",
+		_units: map[string]int64{
+			"ns": 1, "us": 1e3, "µs": 1e3, "ms": 1e6, "s": 1e9, "m": 60e9, "h": 3600e9,
+		},
+	}
+	// _unitFamilySI is dimensionless, its base unit is the empty one.
+	_unitFamilySI = unitFamily{
+		_varName: "_filterSIUnits",
+		_units: map[string]int64{
+			"": 1, "k": 1e3, "K": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15,
+		},
+	}
+)
+
+const _unitSI = "si"
+
+// resolveUnit returns the family of the unit tag option and the base unit of the field.
+func resolveUnit(unit string) (unitFamily, string, error) {
+	if unit == _unitSI {
+		return _unitFamilySI, "", nil
+	}
+	for _, family := range []unitFamily{_unitFamilyBytes, _unitFamilyTime} {
+		if _, ok := family._units[unit]; ok {
+			return family, unit, nil
+		}
+	}
+	return unitFamily{}, "", fmt.Errorf("unknown unit %q", unit)
+}
+
+func validateUnit(field _field) error {
+	if field._qf._unit == "" {
+		return nil
+	}
+	switch classifyGoType(fieldBasicType(field)) {
+	case _goTypeClassInt, _goTypeClassUint, _goTypeClassFloat:
+	default:
+		return fmt.Errorf("unit option is not supported for type %s", field._goType)
+	}
+	_, _, err := resolveUnit(field._qf._unit)
+	return err
+}
+
+// unitLiteral converts a tag literal such as 1GB into the base unit of the field.
+func unitLiteral(field _field, s string) (string, error) {
+	family, base, err := resolveUnit(field._qf._unit)
+	if err != nil {
+		return "", err
+	}
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') && s[i-1] != '.' {
+		i--
+	}
+	number, unit := s[:i], s[i:]
+	if unit == "" || unit == base {
+		return number, nil
+	}
+	factor, ok := family._units[unit]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", unit)
+	}
+	v, ok := new(big.Rat).SetString(number)
+	if !ok || !isPlainDecimal(number) {
+		return "", fmt.Errorf("invalid number %q", number)
+	}
+	v.Mul(v, big.NewRat(factor, family._units[base]))
+	if v.IsInt() {
+		return v.Num().String(), nil
+	}
+	f, _ := v.Float64()
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+// isPlainDecimal reports whether s is a decimal number without exponent, such as -1.5.
+func isPlainDecimal(s string) bool {
+	return strings.TrimLeft(strings.TrimPrefix(s, "-"), "0123456789.") == ""
+}
+
+func generateUnitTable(family unitFamily) string {
+	units := make([]string, 0, len(family._units))
+	for unit := range family._units {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	rows := []string{fmt.Sprintf("var %s = map[string]int64{", family._varName)}
+	for _, unit := range units {
+		rows = append(rows, fmt.Sprintf("%s: %d,", strconv.Quote(unit), family._units[unit]))
+	}
+	rows = append(rows, "}")
+	return strings.Join(rows, "\n")
+}
+
+// generateUnitParseFunc wraps parseFunc of the field into a function accepting unit suffixes.
+func generateUnitParseFunc(field _field, parseFunc string) string {
+	family, base, _ := resolveUnit(field._qf._unit)
+	return fmt.Sprintf("func(inp string) (%s, error) { return gunitparse(inp, %s, %s, %s) }",
+		field._goType, family._varName, strconv.Quote(base), parseFunc)
+}
+
+const genericUnitParseFunc = `
+// gunitparse parses numbers with an optional unit suffix, e.g. 10MB or 1.5k, converting them
+// into the base unit with exact arithmetic. Numbers without a suffix are already in the base unit.
+func gunitparse[T any](inp string, units map[string]int64, base string, parse func(string) (T, error)) (T, error) {
+	i := len(inp)
+	for i > 0 && (inp[i-1] < '0' || inp[i-1] > '9') && inp[i-1] != '.' {
+		i--
+	}
+	number, unit := strings.TrimSpace(inp[:i]), strings.TrimSpace(inp[i:])
+	if unit == "" || unit == base {
+		return parse(number)
+	}
+	factor, ok := units[unit]
+	if !ok {
+		return *new(T), fmt.Errorf("unknown unit %q", unit)
+	}
+	// big.Rat also reads exponents, fractions and hex numbers, which are not combined with units
+	v, ok := new(big.Rat).SetString(number)
+	if !ok || strings.TrimLeft(strings.TrimPrefix(number, "-"), "0123456789.") != "" {
+		return *new(T), fmt.Errorf("invalid number %q", number)
+	}
+	v.Mul(v, big.NewRat(factor, units[base]))
+	if v.IsInt() {
+		return parse(v.Num().String())
+	}
+	f, _ := v.Float64()
+	parsed, err := parse(strconv.FormatFloat(f, 'g', -1, 64))
+	if err != nil {
+		return *new(T), fmt.Errorf("%q is not a whole number of %q", inp, base)
+	}
+	return parsed, nil
+}
+`
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_numericLiteral(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		field  _field
+		input  string
+		want   string
+		hasErr bool
+	}{
+		{name: "plain", field: _field{_goType: "uint64"}, input: "10", want: "10"},
+		{name: "bytes", field: _field{_goType: "uint64", _qf: utiQueryFilter{_unit: "B"}}, input: "1.5KB", want: "1500"},
+		{name: "binary bytes into MB", field: _field{_goType: "float64", _qf: utiQueryFilter{_unit: "MB"}}, input: "1MiB", want: "1.048576"},
+		{name: "si", field: _field{_goType: "int", _qf: utiQueryFilter{_unit: "si"}}, input: "2k", want: "2000"},
+		{name: "time units", field: _field{_goType: "int64", _qf: utiQueryFilter{_unit: "ms"}}, input: "1m", want: "60000"},
+		{name: "fraction of int", field: _field{_goType: "int64", _qf: utiQueryFilter{_unit: "s"}}, input: "1ms", hasErr: true},
+		{name: "exponent with unit", field: _field{_goType: "int", _qf: utiQueryFilter{_unit: "si"}}, input: "1e3k", hasErr: true},
+		{name: "unknown unit", field: _field{_goType: "int64", _qf: utiQueryFilter{_unit: "B"}}, input: "1ms", hasErr: true},
+		{name: "duration", field: _field{_goType: "time.Duration"}, input: "1m30s", want: "time.Duration(90000000000)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got, err := numericLiteral(test.field, test.input)
+
+			// Assert
+			if test.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func Test_validateUnit(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validateUnit(_field{_goType: "uint", _qf: utiQueryFilter{_unit: "KiB"}}))
+	require.Error(t, validateUnit(_field{_goType: "uint", _qf: utiQueryFilter{_unit: "parsec"}}))
+	require.Error(t, validateUnit(_field{_goType: "string", _qf: utiQueryFilter{_unit: "B"}}))
+	require.Error(t, validateUnit(_field{_goType: "time.Duration", _qf: utiQueryFilter{_unit: "s"}}))
+}
+
+func TestUnitConversions(t *testing.T) {
+	t.Parallel()
+
+	const src = "package main\n\ntype Product struct {\n" +
+		"\tSize    uint64  `ufi:\"kind=range;key=size;unit=B\"`\n" +
+		"\tViews   float64 `ufi:\"kind=range;key=views;unit=si\"`\n" +
+		"\tLatency int64   `ufi:\"kind=range;key=latency;unit=ms\"`\n}\n"
+	const main = `package main
+
+import "fmt"
+
+func main() {
+	for _, query := range []string{
+		"size-from=1.5KB&size-to=1MiB",
+		"size-from=10&views-from=2.5k&views-to=1e4",
+		"latency-from=1.5s&latency-to=1m",
+		"views-from=1e3k",
+		"views-from=0x10k",
+		"size-from=1.5B",
+		"latency-from=1us",
+		"size-from=2parsec",
+	} {
+		f, err := ParseFiltersQuery(query)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(f.GetSizeGte(), f.GetSizeLte(), f.GetViewsGte(), f.GetViewsLte(), f.GetLatencyGte(), f.GetLatencyLte())
+	}
+}
+`
+
+	// Act
+	got := runGenerated(t, src, "Product", main)
+
+	// Assert
+	require.Equal(t, `1500 1048576 0 0 0 0
+10 0 2500 10000 0 0
+0 0 0 0 1500 60000
+filter "views-from" (field Views): invalid value "1e3k": invalid number "1e3"
+filter "views-from" (field Views): invalid value "0x10k": invalid number "0x10"
+filter "size-from" (field Size): invalid value "1.5B": strconv.ParseUint: parsing "1.5": invalid syntax
+filter "latency-from" (field Latency): invalid value "1us": "1us" is not a whole number of "ms"
+filter "size-from" (field Size): invalid value "2parsec": unknown unit "parsec"
+`, got)
+}